@@ -0,0 +1,227 @@
+package progressbar
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ColorMode is the color palette supported by the terminal, RGB colors are downgraded to the nearest
+// color of the palette.
+type ColorMode int
+
+const (
+	// ColorModeAuto detects the mode from the COLORTERM and TERM environment variables (see DetectColorMode()).
+	ColorModeAuto ColorMode = iota
+	// Color16 is the basic 16 colors (8 normal and 8 bright) palette.
+	Color16
+	// Color256 is the xterm 256 colors palette.
+	Color256
+	// TrueColor is 24 bits RGB.
+	TrueColor
+)
+
+// RGB is a 24 bits color.
+type RGB struct {
+	R, G, B uint8
+}
+
+// Some RGB colors, e.g. for gradients.
+var (
+	RGBRed    = RGB{220, 50, 47}
+	RGBYellow = RGB{230, 200, 20}
+	RGBGreen  = RGB{40, 190, 70}
+	RGBBlue   = RGB{40, 110, 230}
+	RGBWhite  = RGB{240, 240, 240}
+	// DefaultBackground is the grey background used for the empty part of the bar.
+	DefaultBackground = RGB{128, 128, 128}
+)
+
+// ColorStop is a color at a given percentage (0-100) of a gradient.
+type ColorStop struct {
+	At    float64
+	Color RGB
+}
+
+// RedYellowGreen is a gradient going from red at 0% to yellow at 50% and green at 100%.
+var RedYellowGreen = []ColorStop{{0, RGBRed}, {50, RGBYellow}, {100, RGBGreen}}
+
+var (
+	detectOnce    sync.Once
+	detectedColor ColorMode
+)
+
+// DetectColorMode returns the color mode supported by the terminal according to the COLORTERM
+// (truecolor or 24bit) and TERM (*256color*, *truecolor*, *24bit*, *direct*) environment variables.
+// The result is computed once and cached.
+func DetectColorMode() ColorMode {
+	detectOnce.Do(func() {
+		detectedColor = detectColorMode(os.Getenv("COLORTERM"), os.Getenv("TERM"))
+	})
+	return detectedColor
+}
+
+func detectColorMode(colorTerm, term string) ColorMode {
+	switch strings.ToLower(colorTerm) {
+	case "truecolor", "24bit":
+		return TrueColor
+	}
+	term = strings.ToLower(term)
+	switch {
+	case strings.Contains(term, "truecolor"), strings.Contains(term, "24bit"), strings.Contains(term, "direct"):
+		return TrueColor
+	case strings.Contains(term, "256color"):
+		return Color256
+	default:
+		return Color16
+	}
+}
+
+func (m ColorMode) resolve() ColorMode {
+	if m == ColorModeAuto {
+		return DetectColorMode()
+	}
+	return m
+}
+
+// Fg returns the ANSI sequence to use c as foreground color, in the given mode (ColorModeAuto to detect).
+func (c RGB) Fg(mode ColorMode) string {
+	return "\033[" + c.params(mode, false) + "m"
+}
+
+// Bg returns the ANSI sequence to use c as background color, in the given mode (ColorModeAuto to detect).
+func (c RGB) Bg(mode ColorMode) string {
+	return "\033[" + c.params(mode, true) + "m"
+}
+
+// BarColor returns the ANSI sequence for a fg color bar on a bg background, suitable for Config.Color,
+// in the given mode (ColorModeAuto to detect).
+func BarColor(fg, bg RGB, mode ColorMode) string {
+	return "\033[" + fg.params(mode, false) + ";" + bg.params(mode, true) + "m"
+}
+
+// params returns the SGR parameters for c as foreground or background color.
+func (c RGB) params(mode ColorMode, bg bool) string {
+	switch mode.resolve() {
+	case TrueColor:
+		if bg {
+			return fmt.Sprintf("48;2;%d;%d;%d", c.R, c.G, c.B)
+		}
+		return fmt.Sprintf("38;2;%d;%d;%d", c.R, c.G, c.B)
+	case Color256:
+		if bg {
+			return fmt.Sprintf("48;5;%d", c.Nearest256())
+		}
+		return fmt.Sprintf("38;5;%d", c.Nearest256())
+	default:
+		n := int(c.Nearest16())
+		base := 30
+		if n >= 8 {
+			base = 90 - 8
+		}
+		if bg {
+			base += 10
+		}
+		return fmt.Sprint(base + n)
+	}
+}
+
+// palette16 is the xterm default values of the 16 basic colors.
+var palette16 = [16]RGB{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the component values of the 6x6x6 color cube of the 256 colors palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// Palette256 returns the RGB value of the xterm 256 colors palette index n.
+func Palette256(n uint8) RGB {
+	switch {
+	case n < 16:
+		return palette16[n]
+	case n < 232:
+		n -= 16
+		return RGB{cubeLevels[n/36], cubeLevels[(n/6)%6], cubeLevels[n%6]}
+	default:
+		v := 8 + 10*(n-232)
+		return RGB{v, v, v}
+	}
+}
+
+// Nearest16 returns the index (0-15) of the closest color of the basic 16 colors palette.
+func (c RGB) Nearest16() uint8 {
+	best, bestDist := 0, -1
+	for i, p := range palette16 {
+		if d := c.dist(p); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return uint8(best) //nolint:gosec // less than 16.
+}
+
+// Nearest256 returns the index of the closest color in the 256 colors palette (color cube or grey ramp).
+func (c RGB) Nearest256() uint8 {
+	ci := func(v uint8) uint8 {
+		best := 0
+		for i, l := range cubeLevels {
+			if absDiff(v, l) < absDiff(v, cubeLevels[best]) {
+				best = i
+			}
+		}
+		return uint8(best) //nolint:gosec // less than 6.
+	}
+	cube := 16 + 36*ci(c.R) + 6*ci(c.G) + ci(c.B)
+	avg := (int(c.R) + int(c.G) + int(c.B)) / 3
+	grey := 232
+	if avg > 8 {
+		grey = 232 + (avg-8+5)/10
+	}
+	if grey > 255 {
+		grey = 255
+	}
+	greyIdx := uint8(grey) //nolint:gosec // clamped to 255.
+	if c.dist(Palette256(greyIdx)) < c.dist(Palette256(cube)) {
+		return greyIdx
+	}
+	return cube
+}
+
+func (c RGB) dist(o RGB) int {
+	dr, dg, db := int(c.R)-int(o.R), int(c.G)-int(o.G), int(c.B)-int(o.B)
+	return dr*dr + dg*dg + db*db
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// blend returns the color between a (t=0) and b (t=1).
+func blend(a, b RGB, t float64) RGB {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return RGB{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B)}
+}
+
+// gradientColor returns the color at percentage p of the gradient: the blend of the surrounding stops
+// if blending, or the color of the last stop at or before p otherwise.
+func gradientColor(stops []ColorStop, p float64, blending bool) RGB {
+	if p <= stops[0].At {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if p < stops[i].At {
+			if !blending {
+				return stops[i-1].Color
+			}
+			prev := stops[i-1]
+			return blend(prev.Color, stops[i].Color, (p-prev.At)/(stops[i].At-prev.At))
+		}
+	}
+	return stops[len(stops)-1].Color
+}
@@ -0,0 +1,129 @@
+package progressbar
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// SetTotal sets (or updates, for instance when it becomes known during the processing) the expected
+// total count. Use 0 or a negative value for an unknown total (spinner and counts only).
+// This is thread safe.
+func (bar *Bar) SetTotal(total int64) {
+	atomic.StoreInt64(&bar.total, total)
+	atomic.StoreInt32(&bar.counting, 1)
+}
+
+// Total returns the expected total count (0 or negative if unknown).
+func (bar *Bar) Total() int64 {
+	return atomic.LoadInt64(&bar.total)
+}
+
+// Current returns the current count.
+func (bar *Bar) Current() int64 {
+	return atomic.LoadInt64(&bar.current)
+}
+
+// Add adds n to the current count and updates the progress bar accordingly.
+// This is thread safe and can be called from many goroutines concurrently.
+// The elapsed time (for speed and ETA) starts counting from the first non 0 Add().
+// When the background renderer is running (see Start()), this only records the new count and
+// otherwise the output lock is only acquired when a redraw is due (see Config.UpdateInterval).
+func (bar *Bar) Add(n int64) {
+	current := atomic.AddInt64(&bar.current, n)
+	if current == n && n > 0 {
+		atomic.StoreInt64(&bar.startNano, time.Now().UnixNano())
+	}
+	setFlag(&bar.counting)
+	if atomic.LoadInt32(&bar.rendering) != 0 {
+		setFlag(&bar.dirty)
+		return
+	}
+	// Lock free check that a redraw is due (the percentage is recomputed under the lock).
+	if bar.skipUpdateFast(bar.countDone(current), float64(current)) {
+		return
+	}
+	bar.out.Lock()
+	bar.progress(bar.countPercent()) // computed under the lock so the last update wins.
+	bar.out.Unlock()
+}
+
+// Increment adds 1 to the current count, see Add().
+func (bar *Bar) Increment() {
+	bar.Add(1)
+}
+
+// setFlag sets the atomic flag if not already set (loads are much cheaper than stores
+// on cache lines shared between goroutines).
+func setFlag(flag *int32) {
+	if atomic.LoadInt32(flag) == 0 {
+		atomic.StoreInt32(flag, 1)
+	}
+}
+
+// countPercent returns the percentage of current vs total or -1 when the total isn't known.
+func (bar *Bar) countPercent() float64 {
+	total := bar.Total()
+	if total <= 0 {
+		return -1
+	}
+	return float64(bar.Current()) * 100. / float64(total)
+}
+
+// countDone returns isDone() for the percentage of the count, without the (comparatively slow) division.
+func (bar *Bar) countDone(count int64) bool {
+	total := bar.Total()
+	return total > 0 && float64(count)*100. > 99.999*float64(total)
+}
+
+// CountExtra provides the extra information for count based bars: "current/total unit",
+// rate and estimated time left. See NewCountBar().
+func (bar *Bar) CountExtra(_ *Bar, progressPercent float64) string {
+	unit := bar.unit()
+	current, total := bar.Current(), bar.Total()
+	if current == 0 {
+		if total > 0 {
+			return fmt.Sprintf(" 0/%d %s", total, unit)
+		}
+		return " 0 " + unit
+	}
+	elapsed := bar.elapsed()
+	speed := bar.speed()
+	switch {
+	case total <= 0:
+		return fmt.Sprintf(" %d %s, %s elapsed, %.1f %s/s  ", current, unit, HumanDuration(elapsed), speed, unit)
+	case !isDone(progressPercent):
+		timeLeft, _ := bar.eta(progressPercent)
+		return fmt.Sprintf(" %d/%d %s, %s elapsed, %.1f %s/s, %s remaining  ",
+			current, total, unit, HumanDuration(elapsed), speed, unit, HumanDuration(timeLeft))
+	default:
+		return fmt.Sprintf(" %d %s in %s, %.1f %s/s%s",
+			current, unit, HumanDuration(elapsed), bar.averageSpeed(), unit, bar.clearEOL())
+	}
+}
+
+// CountSummary provides the details of the summary line for count based bars (see Config.OnFinish):
+// count, time and average rate.
+func (bar *Bar) CountSummary(_ *Bar) string {
+	unit := bar.unit()
+	return fmt.Sprintf("%d %s in %s (%.1f %s/s)", bar.Current(), unit, HumanDuration(bar.elapsed()), bar.averageSpeed(), unit)
+}
+
+// unit returns the Unit or "items" by default.
+func (bar *Bar) unit() string {
+	if bar.Unit == "" {
+		return "items"
+	}
+	return bar.Unit
+}
+
+// NewCountBar creates a new count based progress bar from the config, for the expected total
+// (which can also be set or updated later with SetTotal()). Update it with Add() or Increment().
+// It shows "current/total unit" (see Config.Unit), rate and ETA using CountExtra (and CountSummary for the summary).
+func (cfg Config) NewCountBar(total int64) *Bar {
+	bar := cfg.NewBar()
+	bar.Extra = bar.CountExtra
+	bar.Summary = bar.CountSummary
+	bar.SetTotal(total)
+	return bar
+}
@@ -0,0 +1,57 @@
+package progressbar
+
+import (
+	"os"
+	"strings"
+)
+
+// ConfigFromEnv returns cfg adjusted according to the usual terminal environment variables conventions:
+//   - NO_COLOR set and non empty: no colors (see https://no-color.org/).
+//   - CLICOLOR=0: no colors.
+//   - TERM=dumb: no ANSI sequences at all (NoAnsi).
+//   - FORCE_COLOR or CLICOLOR_FORCE set and non empty (and not "0" or "false"): colors and ANSI sequences
+//     even when the output isn't a terminal (ForceTTY). This takes precedence over the above.
+//     FORCE_COLOR=0 (or false) disables colors while CLICOLOR_FORCE=0 is the same as not set.
+//
+// DefaultConfig() already applies it, use this for configs built from scratch.
+func ConfigFromEnv(cfg Config) Config {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("CLICOLOR") == "0" {
+		cfg.UseColors = false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		cfg.NoAnsi = true
+	}
+	force, isSet := forceColorEnv()
+	if !isSet {
+		return cfg
+	}
+	if !force {
+		cfg.UseColors = false
+		return cfg
+	}
+	cfg.UseColors = true
+	cfg.NoAnsi = false
+	cfg.ForceTTY = true
+	return cfg
+}
+
+// forceColorEnv returns whether FORCE_COLOR or CLICOLOR_FORCE is requesting colors, isSet is false
+// when neither is set (CLICOLOR_FORCE=0 meaning not forcing, as opposed to FORCE_COLOR=0 disabling colors).
+func forceColorEnv() (force, isSet bool) {
+	for _, name := range []string{"FORCE_COLOR", "CLICOLOR_FORCE"} {
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			continue
+		}
+		switch strings.ToLower(v) {
+		case "0", "false", "no", "off":
+			if name == "CLICOLOR_FORCE" {
+				continue
+			}
+			return false, true
+		default:
+			return true, true
+		}
+	}
+	return false, false
+}
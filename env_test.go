@@ -0,0 +1,42 @@
+package progressbar_test
+
+import (
+	"os"
+	"testing"
+
+	"fortio.org/progressbar"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name                     string
+		env                      map[string]string
+		colors, noAnsi, forceTTY bool
+	}{
+		{"none", nil, true, false, false},
+		{"NO_COLOR", map[string]string{"NO_COLOR": "1"}, false, false, false},
+		{"CLICOLOR=0", map[string]string{"CLICOLOR": "0"}, false, false, false},
+		{"dumb", map[string]string{"TERM": "dumb"}, true, true, false},
+		{"FORCE_COLOR", map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1", "TERM": "dumb"}, true, false, true},
+		{"FORCE_COLOR=0", map[string]string{"FORCE_COLOR": "0"}, false, false, false},
+		{"CLICOLOR_FORCE", map[string]string{"CLICOLOR_FORCE": "1"}, true, false, true},
+		{"CLICOLOR_FORCE=0", map[string]string{"CLICOLOR_FORCE": "0"}, true, false, false},
+		{"CLICOLOR_FORCE=false NO_COLOR", map[string]string{"CLICOLOR_FORCE": "false", "NO_COLOR": "1"}, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"NO_COLOR", "CLICOLOR", "TERM", "FORCE_COLOR", "CLICOLOR_FORCE"} {
+				t.Setenv(name, "") // restored after the test.
+				_ = os.Unsetenv(name)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := progressbar.ConfigFromEnv(progressbar.Config{UseColors: true})
+			if cfg.UseColors != tt.colors || cfg.NoAnsi != tt.noAnsi || cfg.ForceTTY != tt.forceTTY {
+				t.Errorf("got UseColors %v NoAnsi %v ForceTTY %v, want %v %v %v",
+					cfg.UseColors, cfg.NoAnsi, cfg.ForceTTY, tt.colors, tt.noAnsi, tt.forceTTY)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package progressbar
+
+import (
+	"math"
+	"time"
+)
+
+// Estimator estimates the rate of progress (in units, for instance bytes, per second) from
+// samples of the current count. It is used for both the displayed speed and the remaining time.
+// Estimators are stateful so each Bar needs its own instance (see Bar.Estimator).
+// They are called with the bar's output lock held so implementations do not need their own locking.
+type Estimator interface {
+	// Sample records the current count at elapsed time since the start of the progress.
+	Sample(elapsed time.Duration, current int64)
+	// Rate returns the estimated rate in units per second (0 if not known yet).
+	Rate() float64
+}
+
+// AverageEstimator is the whole transfer average: current count divided by elapsed time.
+// It's what is used when Bar.Estimator is nil.
+type AverageEstimator struct {
+	rate float64
+}
+
+// NewAverageEstimator returns a new whole transfer average estimator.
+func NewAverageEstimator() *AverageEstimator {
+	return &AverageEstimator{}
+}
+
+func (e *AverageEstimator) Sample(elapsed time.Duration, current int64) {
+	if elapsed <= 0 {
+		return
+	}
+	e.rate = float64(current) / elapsed.Seconds()
+}
+
+func (e *AverageEstimator) Rate() float64 {
+	return e.rate
+}
+
+// EWMAEstimator is an exponentially weighted moving average of the rate between samples,
+// where the weight of past rates halves every HalfLife. It reacts to speed changes and stalls
+// much faster than the average on long transfers while still smoothing out the noise.
+type EWMAEstimator struct {
+	// Time for the weight of past samples to decrease by half.
+	HalfLife time.Duration
+	// Weighted sum of the rates and sum of the weights (to not bias toward the initial 0).
+	rate        float64
+	weight      float64
+	lastElapsed time.Duration
+	lastCount   int64
+}
+
+// DefaultHalfLife is the EWMAEstimator HalfLife used when 0.
+const DefaultHalfLife = 3 * time.Second
+
+// NewEWMAEstimator returns a new exponentially weighted moving average estimator
+// with the given half life (0 for DefaultHalfLife).
+func NewEWMAEstimator(halfLife time.Duration) *EWMAEstimator {
+	return &EWMAEstimator{HalfLife: halfLife}
+}
+
+func (e *EWMAEstimator) Sample(elapsed time.Duration, current int64) {
+	if elapsed < e.lastElapsed {
+		// Restart.
+		*e = EWMAEstimator{HalfLife: e.HalfLife}
+	}
+	dt := elapsed - e.lastElapsed
+	if dt <= 0 {
+		return
+	}
+	halfLife := e.HalfLife
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	instant := float64(current-e.lastCount) / dt.Seconds()
+	// Weight proportional to the time covered by the sample so irregular sampling doesn't skew the rate.
+	alpha := 1 - math.Exp2(-float64(dt)/float64(halfLife))
+	e.rate += alpha * (instant - e.rate)
+	e.weight += alpha * (1 - e.weight)
+	e.lastElapsed, e.lastCount = elapsed, current
+}
+
+func (e *EWMAEstimator) Rate() float64 {
+	if e.weight <= 0 {
+		return 0
+	}
+	return e.rate / e.weight
+}
+
+// WindowEstimator computes the rate over a sliding time Window (for instance the last 10 seconds).
+type WindowEstimator struct {
+	// Duration of the sliding window.
+	Window  time.Duration
+	samples []rateSample
+}
+
+type rateSample struct {
+	elapsed time.Duration
+	current int64
+}
+
+// DefaultWindow is the WindowEstimator Window used when 0.
+const DefaultWindow = 10 * time.Second
+
+// NewWindowEstimator returns a new sliding window estimator for the given window (0 for DefaultWindow).
+func NewWindowEstimator(window time.Duration) *WindowEstimator {
+	return &WindowEstimator{Window: window}
+}
+
+func (e *WindowEstimator) Sample(elapsed time.Duration, current int64) {
+	if elapsed <= 0 {
+		return
+	}
+	n := len(e.samples)
+	if n == 0 || elapsed < e.samples[n-1].elapsed {
+		// Start (or restart) from the origin.
+		e.samples = append(e.samples[:0], rateSample{})
+	}
+	e.samples = append(e.samples, rateSample{elapsed, current})
+	window := e.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	// Drop samples older than the window but keep at least 2 to compute a rate.
+	drop := 0
+	for drop < len(e.samples)-2 && elapsed-e.samples[drop+1].elapsed >= window {
+		drop++
+	}
+	if drop > 0 {
+		e.samples = append(e.samples[:0], e.samples[drop:]...)
+	}
+}
+
+func (e *WindowEstimator) Rate() float64 {
+	n := len(e.samples)
+	if n < 2 {
+		return 0
+	}
+	first, last := e.samples[0], e.samples[n-1]
+	dt := last.elapsed - first.elapsed
+	if dt <= 0 {
+		return 0
+	}
+	return float64(last.current-first.current) / dt.Seconds()
+}
+
+// Compile check time of interface implementations.
+var (
+	_ Estimator = &AverageEstimator{}
+	_ Estimator = &EWMAEstimator{}
+	_ Estimator = &WindowEstimator{}
+)
@@ -28,6 +28,7 @@ func main() {
 func Main() int {
 	noAnsiFlag := flag.Bool("no-ansi", false, "Disable ANSI escape codes (colors and cursor movement)")
 	delayFlag := flag.Duration("delay", 5*time.Millisecond, "Artificially slowdown writes with this delay")
+	estimatorFlag := flag.String("estimator", "average", "Rate estimator to use: average, ewma or window")
 	flag.Parse()
 	if flag.NArg() != 1 {
 		return usage()
@@ -48,29 +49,44 @@ func Main() int {
 	}
 	cfg := progressbar.DefaultConfig()
 	cfg.NoAnsi = *noAnsiFlag
+	cfg.Indeterminate = true // in case the server doesn't send a Content-Length.
 	cfg.Prefix = "R "
 	cfg.ScreenWriter = os.Stderr
 	rBar := cfg.NewBar()
 	// On purpose different buffer size than the writer to show the effect of different speeds.
 	bufSize := 10 * 1024 * 1024 // 10MB
 	reader := progressbar.NewAutoReader(rBar, resp.Body, resp.ContentLength)
+	rBar.Estimator = newEstimator(*estimatorFlag)
 	defer reader.Close()
 	cfg.Prefix = "W "
 	wBar := cfg.NewBar()
 	writer := progressbar.NewAutoWriter(wBar, bufio.NewWriterSize(os.Stdout, 16*1024), resp.ContentLength)
-	cfg.NewMultiBar(rBar, wBar)
+	wBar.Estimator = newEstimator(*estimatorFlag)
+	mbar := cfg.NewMultiBar(rBar, wBar)
 	if resp.StatusCode != http.StatusOK {
 		fmt.Fprintf(os.Stderr, "Error fetching %s: %s\n", url, resp.Status)
 		return 1
 	}
 	err = AsyncCopy(writer, reader, bufSize, 100, *delayFlag)
-	writer.End()
+	mbar.End() // the bars are marked failed automatically upon errors.
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing: %v\n", err)
 	}
 	return 0
 }
 
+// newEstimator returns a new estimator of the given kind (each bar needs its own).
+func newEstimator(kind string) progressbar.Estimator {
+	switch kind {
+	case "ewma":
+		return progressbar.NewEWMAEstimator(0)
+	case "window":
+		return progressbar.NewWindowEstimator(0)
+	default:
+		return progressbar.NewAverageEstimator()
+	}
+}
+
 // AsyncCopy is a fairly overly complicated replacement for io.Copy that decouples
 // reader and writer and optionally delays the writer.
 func AsyncCopy(dst io.Writer, src io.Reader, bufSize, chanSize int, delay time.Duration) error {
@@ -0,0 +1,41 @@
+// Demonstrate a count based progress bar updated concurrently by a pool of workers.
+package main
+
+import (
+	"flag"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"fortio.org/progressbar"
+)
+
+func main() {
+	numFlag := flag.Int64("n", 500, "Number of items to process")
+	workersFlag := flag.Int("workers", 8, "Number of concurrent workers")
+	jsonFlag := flag.Bool("json", false, "Output JSON events instead of the progress bar")
+	flag.Parse()
+	cfg := progressbar.DefaultConfig()
+	cfg.JSONEvents = *jsonFlag
+	cfg.Prefix = "Processing "
+	cfg.Unit = "records"
+	pb := cfg.NewCountBar(*numFlag)
+	items := make(chan int64)
+	wg := sync.WaitGroup{}
+	for i := 0; i < *workersFlag; i++ {
+		wg.Add(1)
+		go func() {
+			for range items {
+				time.Sleep(time.Duration(5+rand.IntN(40)) * time.Millisecond) //nolint:gosec // not crypto...
+				pb.Increment()
+			}
+			wg.Done()
+		}()
+	}
+	for i := int64(0); i < *numFlag; i++ {
+		items <- i
+	}
+	close(items)
+	wg.Wait()
+	pb.End()
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"math/rand/v2"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 func UpdateBar(b *progressbar.Bar, delay time.Duration) {
 	for i := 0; i <= 300; i++ {
 		b.Progress(float64(i) / 3.)
+		if i > 0 && i%150 == 0 {
+			log.Printf("%s is %d%% done", strings.TrimSpace(b.Prefix), i/3)
+		}
 		time.Sleep(delay)
 	}
 }
@@ -21,11 +26,14 @@ func UpdateBar(b *progressbar.Bar, delay time.Duration) {
 func main() {
 	noColorFlag := flag.Bool("no-color", false, "Disable color in the progress bars")
 	extraLinesFlag := flag.Int("extra", 1, "Extra lines between each progress bars")
+	taskbarFlag := flag.Bool("taskbar", false, "Show the overall progress in the terminal taskbar and title")
 	flag.Parse()
 	fmt.Println("Multi progress bar example" + progressbar.ClearAfter)
 	cfg := progressbar.DefaultConfig()
 	cfg.ExtraLines = *extraLinesFlag
 	cfg.UseColors = !*noColorFlag
+	cfg.TaskbarProgress = *taskbarFlag
+	cfg.TitleProgress = *taskbarFlag
 	cfg.ScreenWriter = os.Stdout
 	cfg.UpdateInterval = 0 // Update immediately as we're simulating sleep and it's a demo.
 	mbar := cfg.NewMultiBarPrefixes(
@@ -34,6 +42,8 @@ func main() {
 		"short",
 		"b4",
 	)
+	// Log lines are printed above the bars:
+	log.SetOutput(mbar.Writer())
 	wg := sync.WaitGroup{}
 	colors := []string{progressbar.RedBar, progressbar.GreenBar, progressbar.YellowBar, progressbar.BlueBar, progressbar.WhiteBar}
 	for i, bar := range mbar.Bars {
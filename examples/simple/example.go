@@ -30,13 +30,35 @@ func main() {
 	moveUpFlag := flag.Bool("moveup", false, "Demo in place move instead of writer")
 	noPercent := flag.Bool("no-percent", false, "Disable percent display")
 	noSuffix := flag.Bool("no-suffix", false, "Disable suffix display")
+	autoWidthFlag := flag.Bool("auto-width", false, "Fit the progress bar line in the terminal width")
+	gradientFlag := flag.Bool("gradient", false, "Use a red to yellow to green blended gradient (implies -color)")
+	themeFlag := flag.String("theme", "default", "Bar theme: default, ascii, braille, shaded or dots")
+	spinnerFlag := flag.String("spinner", "dots", "Spinner style: dots, line, arc, bouncing-bar or clock")
+	renderFlag := flag.Bool("render", false, "Use the background renderer instead of drawing on each update")
 	flag.Parse()
 	cfg := progressbar.DefaultConfig()
 	cfg.UseColors = *colorFlag
 	cfg.NoAnsi = *noAnsiFlag
 	cfg.NoPercent = *noPercent
+	cfg.AutoWidth = *autoWidthFlag
+	themes := map[string]*progressbar.Theme{
+		"ascii":   &progressbar.ASCIITheme,
+		"braille": &progressbar.BrailleTheme,
+		"shaded":  &progressbar.ShadedTheme,
+		"dots":    &progressbar.DotsTheme,
+	}
+	cfg.Theme = themes[*themeFlag] // nil (default theme) if not found.
+	cfg.SpinnerStyle = progressbar.SpinnerStyles[*spinnerFlag]
+	if *gradientFlag {
+		cfg.UseColors = true
+		cfg.Gradient = progressbar.RedYellowGreen
+		cfg.GradientBlend = true
+	}
 	cfg.ScreenWriter = os.Stdout // For playground, defaults to stderr otherwise.
 	pb := cfg.NewBar()
+	if *renderFlag {
+		pb.Start()
+	}
 	w := pb.Writer()
 	fmt.Fprintln(w, "Single progress bar example")
 	moveUpMode := *moveUpFlag
@@ -47,7 +69,11 @@ func main() {
 		go PrintStuff(pb, w, *everyFlag)
 	}
 	// exact number of 'pixels', just to demo every smooth step:
-	n := pb.Width * 8
+	steps := len(progressbar.FractionalBlocks)
+	if cfg.Theme != nil {
+		steps = len(cfg.Theme.Fractional)
+	}
+	n := pb.Width * steps
 	for i := 0; i <= n; i++ {
 		if !*noSuffix {
 			pb.UpdateSuffix(fmt.Sprintf(" %d/%d", i, n))
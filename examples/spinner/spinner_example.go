@@ -0,0 +1,40 @@
+// Demonstrate the self animating status spinner with log output printed above it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"fortio.org/progressbar"
+)
+
+func main() {
+	styleFlag := flag.String("spinner", "dots", "Spinner style: dots, line, arc, bouncing-bar or clock")
+	failFlag := flag.Bool("fail", false, "Demo the failure final state")
+	flag.Parse()
+	cfg := progressbar.DefaultConfig()
+	style, ok := progressbar.SpinnerStyles[*styleFlag]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown spinner style %q\n", *styleFlag)
+		os.Exit(1)
+	}
+	cfg.SpinnerStyle = style
+	sp := cfg.NewStatusSpinner("Connecting...")
+	sp.Start(context.Background())
+	w := sp.Writer()
+	steps := []string{"Resolving dependencies...", "Downloading...", "Verifying checksums...", "Installing..."}
+	for i, step := range steps {
+		time.Sleep(time.Second)
+		fmt.Fprintf(w, "Step %d done\n", i+1)
+		sp.UpdateMessage(step)
+	}
+	time.Sleep(time.Second)
+	if *failFlag {
+		sp.Fail("Installation failed")
+		return
+	}
+	sp.Success("Installed")
+}
@@ -0,0 +1,56 @@
+// Demonstrate tree (nested) progress bars: stages made of steps, with the stages progress derived
+// from their steps and completed stages collapsed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"fortio.org/progressbar"
+)
+
+func main() {
+	stagesFlag := flag.Int("stages", 3, "Number of stages")
+	stepsFlag := flag.Int("steps", 4, "Number of steps per stage")
+	keepFlag := flag.Bool("keep", false, "Keep the steps of completed stages on screen")
+	flag.Parse()
+	cfg := progressbar.DefaultConfig()
+	cfg.DeriveFromChildren = true
+	cfg.CollapseOnEnd = !*keepFlag
+	mbar := cfg.NewMultiBarPrefixes("Build")
+	build := mbar.Bars[0]
+	for s := 1; s <= *stagesFlag; s++ {
+		stage := newChild(build, fmt.Sprintf("Stage %d", s))
+		wg := sync.WaitGroup{}
+		for k := 1; k <= *stepsFlag; k++ {
+			step := newChild(stage, fmt.Sprintf("Step %d", k))
+			delay := time.Duration(5+rand.IntN(30)) * time.Millisecond //nolint:gosec // not crypto...
+			wg.Add(1)
+			go func() {
+				for i := 0; i <= 100; i++ {
+					step.Progress(float64(i))
+					time.Sleep(delay)
+				}
+				step.End()
+				wg.Done()
+			}()
+		}
+		wg.Wait()
+		stage.End()
+	}
+	mbar.End()
+}
+
+// newChild adds a child bar, exiting on error (the parents here are always in the multi bar).
+func newChild(parent *progressbar.Bar, prefix string) *progressbar.Bar {
+	child, err := parent.NewChild(prefix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return child
+}
@@ -0,0 +1,169 @@
+package progressbar
+
+import "strings"
+
+// FinishStatus is the final state of a bar, see Bar.Finish().
+type FinishStatus int
+
+const (
+	// NotFinished is the status of a bar still in progress or ended without Finish().
+	NotFinished FinishStatus = iota
+	// FinishSuccess is a completed operation.
+	FinishSuccess
+	// FinishFailure is a failed operation (red bar).
+	FinishFailure
+	// FinishAborted is an operation interrupted before completion, e.g. canceled (yellow bar).
+	FinishAborted
+	// FinishSkipped is an operation that didn't need to run (white bar).
+	FinishSkipped
+)
+
+// Final status symbols, shown in place of the spinner (or before the final message when there is no spinner).
+const (
+	SuccessSymbol = DoneSpinner
+	FailureSymbol = "✗ "
+	AbortedSymbol = "⊘ "
+	SkippedSymbol = "↷ "
+	WarningSymbol = "⚠ "
+	InfoSymbol    = "ℹ "
+)
+
+func (s FinishStatus) String() string {
+	switch s {
+	case FinishSuccess:
+		return "success"
+	case FinishFailure:
+		return "failure"
+	case FinishAborted:
+		return "aborted"
+	case FinishSkipped:
+		return "skipped"
+	default:
+		return "not finished"
+	}
+}
+
+// Symbol returns the symbol for the status (empty for NotFinished).
+func (s FinishStatus) Symbol() string {
+	switch s {
+	case FinishSuccess:
+		return SuccessSymbol
+	case FinishFailure:
+		return FailureSymbol
+	case FinishAborted:
+		return AbortedSymbol
+	case FinishSkipped:
+		return SkippedSymbol
+	default:
+		return ""
+	}
+}
+
+// color returns the bar color for the status, empty when the regular color applies.
+func (s FinishStatus) color() string {
+	switch s {
+	case FinishFailure:
+		return RedBar
+	case FinishAborted:
+		return YellowBar
+	case FinishSkipped:
+		return WhiteBar
+	default:
+		return ""
+	}
+}
+
+// Finish ends the bar (see End()) with the given status: the spinner is replaced by the status symbol and the
+// bar takes the status color (red for failures, etc...). A non empty message replaces the Suffix and Extra
+// information in the final rendering (e.g. the error). Only the first Finish() or End() has an effect.
+func (bar *Bar) Finish(status FinishStatus, message string) {
+	bar.out.Lock()
+	if bar.ended {
+		bar.out.Unlock()
+		return
+	}
+	bar.status = status
+	bar.finalMessage = message
+	if bar.logMode() {
+		bar.logPending = true // End() prints the final line.
+	} else {
+		bar.draw(bar.latestPercent())
+	}
+	bar.out.Unlock()
+	bar.End()
+}
+
+// Status returns the final status of the bar (NotFinished until Finish() or one of its helpers is called).
+func (bar *Bar) Status() FinishStatus {
+	bar.out.Lock()
+	defer bar.out.Unlock()
+	return bar.status
+}
+
+// Succeed ends the bar as successful, see Finish().
+func (bar *Bar) Succeed() {
+	bar.Finish(FinishSuccess, "")
+}
+
+// Fail ends the bar as failed with the error as final message, see Finish().
+func (bar *Bar) Fail(err error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	bar.Finish(FinishFailure, msg)
+}
+
+// Abort ends the bar as aborted, see Finish().
+func (bar *Bar) Abort() {
+	bar.Finish(FinishAborted, "")
+}
+
+// Skip ends the bar as skipped, see Finish().
+func (bar *Bar) Skip() {
+	bar.Finish(FinishSkipped, "")
+}
+
+// FinishAction is what happens to the bar line once ended, see Config.OnFinish.
+type FinishAction int
+
+const (
+	// OnFinishKeep leaves the last rendering of the bar on screen (default).
+	OnFinishKeep FinishAction = iota
+	// OnFinishClear erases the bar line.
+	OnFinishClear
+	// OnFinishSummary replaces the bar line by a compact one line summary, see Bar.Summary.
+	OnFinishSummary
+)
+
+// summaryLine returns the one line summary of the bar: status symbol, prefix, Summary details (elapsed time
+// by default) and the final message if any. Ending a bar without Finish() counts as a success.
+func (bar *Bar) summaryLine() string {
+	status := bar.status
+	if status == NotFinished {
+		status = FinishSuccess
+	}
+	line := status.Symbol() + strings.TrimSpace(bar.Prefix)
+	details := ""
+	if bar.Summary != nil {
+		details = bar.Summary(bar)
+	} else {
+		details = "in " + HumanDuration(bar.elapsed())
+	}
+	if details != "" {
+		line += " " + details
+	}
+	if bar.finalMessage != "" {
+		line += ": " + bar.finalMessage
+	}
+	return line
+}
+
+// clearLine returns the sequence erasing the current line and leaving the cursor at its start.
+func (bar *Bar) clearLine() string {
+	if bar.noAnsi() {
+		width := DisplayWidth(string(bar.render(nil, bar.latestPercent())))
+		return "\r" + strings.Repeat(" ", width) + "\r"
+	}
+	return "\r\033[K"
+}
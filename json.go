@@ -0,0 +1,127 @@
+package progressbar
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// JSONEvent is a line of the Config.JSONEvents output (newline delimited JSON).
+type JSONEvent struct {
+	// Event type: "start" (first update), "progress", "message" (WriteAbove() and Writer() lines) or "finish".
+	Event string `json:"event"`
+	// ID of the bar (see Bar.ID()) and of its parent for tree bars. Omitted for the lines written to a Writer().
+	ID     int64     `json:"id,omitempty"`
+	Parent int64     `json:"parent,omitempty"`
+	Time   time.Time `json:"time"`
+	Prefix string    `json:"prefix,omitempty"`
+	// Current and total counts (count based and AutoProgressReader/Writer bars), percentage (omitted while unknown),
+	// rate in units per second, estimated time remaining and time elapsed in seconds.
+	Current int64    `json:"current,omitempty"`
+	Total   int64    `json:"total,omitempty"`
+	Percent *float64 `json:"percent,omitempty"`
+	Rate    float64  `json:"rate,omitempty"`
+	ETA     float64  `json:"eta,omitempty"`
+	Elapsed float64  `json:"elapsed,omitempty"`
+	// Final status (see FinishStatus, omitted when ended without Finish()) and message, or the message text.
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// lastBarID is the last ID given to a bar, see Bar.ID().
+var lastBarID int64
+
+// newBarID returns the ID for a new bar.
+func newBarID() int64 {
+	return atomic.AddInt64(&lastBarID, 1)
+}
+
+// ID returns the bar's identifier, unique in the process and stable for its lifetime (e.g. to tell apart
+// the bars of a multi bar in the JSONEvents output).
+func (bar *Bar) ID() int64 {
+	return bar.id
+}
+
+// jsonEvent returns an event for the bar.
+func (bar *Bar) jsonEvent(event string) JSONEvent {
+	e := JSONEvent{Event: event, ID: bar.id, Time: time.Now(), Prefix: strings.TrimSpace(bar.Prefix)}
+	if bar.parent != nil {
+		e.Parent = bar.parent.id
+	}
+	return e
+}
+
+// setProgress sets the counts, percentage, rate and times of the event.
+func (e *JSONEvent) setProgress(bar *Bar, progressPercent float64) {
+	e.Current = bar.Current()
+	if total := bar.Total(); total > 0 {
+		e.Total = total
+	}
+	if progressPercent >= 0 {
+		e.Percent = &progressPercent
+	}
+	e.Rate = bar.speed()
+	if eta, ok := bar.eta(progressPercent); ok && !isDone(progressPercent) {
+		e.ETA = eta.Seconds()
+	}
+	e.Elapsed = bar.elapsed().Seconds()
+}
+
+// jsonDraw writes the start event the first time and a progress event when the state changed,
+// called with the output lock held.
+func (bar *Bar) jsonDraw(progressPercent float64) {
+	current := bar.Current()
+	if bar.jsonStarted && progressPercent == bar.jsonPercent && current == bar.jsonCurrent {
+		return // e.g. spinner animation frames.
+	}
+	bar.jsonStart()
+	bar.jsonPercent, bar.jsonCurrent = progressPercent, current
+	e := bar.jsonEvent("progress")
+	e.setProgress(bar, progressPercent)
+	bar.out.writeJSON(e)
+}
+
+// jsonStart writes the start event if not done yet, called with the output lock held.
+func (bar *Bar) jsonStart() {
+	if bar.jsonStarted {
+		return
+	}
+	bar.jsonStarted = true
+	bar.out.writeJSON(bar.jsonEvent("start"))
+}
+
+// jsonFinish writes the finish event, called with the output lock held.
+func (bar *Bar) jsonFinish() {
+	bar.jsonStart()
+	e := bar.jsonEvent("finish")
+	e.setProgress(bar, bar.latestPercent())
+	if bar.status != NotFinished {
+		e.Status = bar.status.String()
+	}
+	e.Message = bar.finalMessage
+	bar.out.writeJSON(e)
+}
+
+// jsonMessage writes a message event for the bar, called with the output lock held.
+func (bar *Bar) jsonMessage(msg string) {
+	e := bar.jsonEvent("message")
+	e.Message = msg
+	bar.out.writeJSON(e)
+}
+
+// writeJSON writes the event on its own line, called with the lock held.
+func (w *writer) writeJSON(e JSONEvent) {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return // only for NaN or infinite values.
+	}
+	_, _ = w.out.Write(append(buf, '\n'))
+}
+
+// writeJSONLines writes a message event for each line, called with the lock held.
+func (w *writer) writeJSONLines(lines []byte) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(lines), "\n"), "\n") {
+		w.writeJSON(JSONEvent{Event: "message", Time: time.Now(), Message: strings.TrimSuffix(line, "\r")})
+	}
+}
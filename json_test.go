@@ -0,0 +1,59 @@
+package progressbar_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"fortio.org/progressbar"
+)
+
+// JSONEvents bars without ScreenWriter get their own writer: the shared one's Writer() lines stay plain text.
+func TestJSONEventsDontShareWriter(t *testing.T) {
+	shared := progressbar.NewBar().Writer()
+	cfg := progressbar.DefaultConfig()
+	cfg.JSONEvents = true
+	if cfg.NewBar().Writer() == shared {
+		t.Error("JSONEvents bar uses the shared writer")
+	}
+	mb := progressbar.DefaultConfig().NewMultiBar(progressbar.NewBar(), cfg.NewBar())
+	if mb.Bars[0].Writer() == shared {
+		t.Error("multi bar with a JSONEvents bar uses the shared writer")
+	}
+}
+
+// Creating bars doesn't touch the shared writer while it's in use (run with -race).
+func TestNewBarSharedWriterRace(t *testing.T) {
+	w := progressbar.NewBar().Writer()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_, _ = w.Write([]byte("\n"))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		progressbar.DefaultConfig().NewBar()
+	}
+	wg.Wait()
+}
+
+func TestJSONEventsWriterLines(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	cfg.JSONEvents = true
+	bar := cfg.NewBar()
+	_, _ = bar.Writer().Write([]byte("hello\n"))
+	bar.End()
+	var e progressbar.JSONEvent
+	if err := json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &e); err != nil {
+		t.Fatalf("first line of %q: %v", buf.String(), err)
+	}
+	if e.Event != "message" || e.Message != "hello" {
+		t.Errorf("got %+v, want the message event for hello", e)
+	}
+}
@@ -0,0 +1,137 @@
+package progressbar
+
+import (
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultLogPercentStep is the default Config.LogPercentStep: a line every 10%.
+	DefaultLogPercentStep = 10.
+	// DefaultLogInterval is the default Config.LogInterval: a line at least every 30s.
+	DefaultLogInterval = 30 * time.Second
+)
+
+// isTerminal returns true if w is a file which is a terminal (character device). Writers that are not
+// files (buffers, pipes wrappers, etc...) are considered terminals, and their ANSI handling left to the
+// Config.NoAnsi setting, as before this detection was introduced.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return true
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// logMode returns true when the bar should print complete lines instead of redrawing in place:
+// when the screen writer isn't a terminal (e.g. CI logs, redirected to a file) unless ForceTTY is set,
+// for multi bars without ANSI (which can't move the cursor to each bar) and when writing JSONEvents instead.
+func (bar *Bar) logMode() bool {
+	return (bar.out.notTTY && !bar.ForceTTY) || (bar.mb != nil && (bar.NoAnsi || bar.mb.NoAnsi)) || bar.JSONEvents
+}
+
+// noAnsi returns true when no ANSI sequences should be emitted (NoAnsi configured or in log mode).
+func (bar *Bar) noAnsi() bool {
+	return bar.NoAnsi || bar.logMode()
+}
+
+// clearEOL returns what to append at the end of a line that may be shorter than the previous one.
+func (bar *Bar) clearEOL() string {
+	switch {
+	case bar.logMode():
+		return ""
+	case bar.NoAnsi:
+		return "                                        " // 40 spaces.
+	default:
+		return "\033[K"
+	}
+}
+
+// logDue returns true if a new line should be printed in log mode: the first update, every
+// LogPercentStep percent, at least every LogInterval and once when done.
+func (bar *Bar) logDue(progressPercent float64, now time.Time) bool {
+	if bar.lastLogTime.IsZero() {
+		return true
+	}
+	if isDone(bar.lastLogPercent) {
+		return false
+	}
+	if isDone(progressPercent) {
+		return true
+	}
+	step := bar.LogPercentStep
+	if step <= 0 {
+		step = DefaultLogPercentStep
+	}
+	if progressPercent >= 0 && math.Floor(progressPercent/step) > math.Floor(bar.lastLogPercent/step) {
+		return true
+	}
+	interval := bar.LogInterval
+	if interval <= 0 {
+		interval = DefaultLogInterval
+	}
+	return now.Sub(bar.lastLogTime) >= interval
+}
+
+// logDraw prints a complete status line if one is due (or force is true), called with the output lock held.
+func (bar *Bar) logDraw(progressPercent float64, force bool) {
+	if bar.mb != nil && bar.mb.LogBlock {
+		bar.logPending = false
+		bar.mb.logBlock(false)
+		return
+	}
+	now := time.Now()
+	if !force && !bar.logDue(progressPercent, now) {
+		bar.logPending = true
+		return
+	}
+	bar.lastLogTime = now
+	bar.lastLogPercent = progressPercent
+	bar.lastLogCurrent = bar.Current()
+	bar.logPending = false
+	bar.out.buf = bar.render(bar.out.buf[:0], progressPercent)
+	bar.out.buf = append(bar.out.buf, '\n')
+	_, _ = bar.out.out.Write(bar.out.buf)
+	bar.out.buf = bar.out.buf[:0]
+}
+
+// logBlock prints the lines of all the bars of the multi bar (see Config.LogBlock) if LogInterval elapsed
+// since the last block (or force is true), called with the output lock held.
+func (mb *MultiBar) logBlock(force bool) {
+	now := time.Now()
+	interval := mb.LogInterval
+	if interval <= 0 {
+		interval = DefaultLogInterval
+	}
+	if !force && !mb.lastBlock.IsZero() && now.Sub(mb.lastBlock) < interval {
+		return
+	}
+	mb.lastBlock = now
+	var buf []byte
+	for _, b := range mb.Bars {
+		buf = b.render(buf, b.latestPercent())
+		buf = append(buf, '\n')
+	}
+	_, _ = mb.out.out.Write(buf)
+}
+
+// logSummary prints the final line of each bar (the summary line with OnFinishSummary) at the end of a
+// multi bar in LogBlock log mode, called with the output lock held.
+func (mb *MultiBar) logSummary() {
+	var buf []byte
+	for _, b := range mb.Bars {
+		if b.OnFinish == OnFinishSummary {
+			buf = append(buf, b.summaryLine()...)
+		} else {
+			buf = b.render(buf, b.latestPercent())
+		}
+		buf = append(buf, '\n')
+	}
+	_, _ = mb.out.out.Write(buf)
+}
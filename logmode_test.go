@@ -0,0 +1,45 @@
+package progressbar_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fortio.org/progressbar"
+)
+
+// In log mode End() prints the last state even when its update was throttled by UpdateInterval.
+func TestLogModeFinalLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(bar *progressbar.Bar)
+		want   string
+	}{
+		{"percent", func(bar *progressbar.Bar) { bar.Progress(50); bar.Progress(55) }, "55.0%"},
+		{"count", func(bar *progressbar.Bar) { bar.SetTotal(100); bar.Add(50); bar.Add(5) }, "55/100"},
+		{"unknown total", func(bar *progressbar.Bar) { bar.Add(50); bar.Add(5) }, " 55 "},
+	}
+	for _, tst := range tests {
+		f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := progressbar.DefaultConfig()
+		cfg.ScreenWriter = f
+		cfg.UpdateInterval = time.Hour
+		bar := cfg.NewBar()
+		if tst.name != "percent" {
+			bar.Extra = bar.CountExtra
+		}
+		tst.update(bar)
+		bar.End()
+		_ = f.Close()
+		out, _ := os.ReadFile(f.Name())
+		lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+		if last := lines[len(lines)-1]; !strings.Contains(last, tst.want) {
+			t.Errorf("%s: last line %q, want %q in it (output %q)", tst.name, last, tst.want, out)
+		}
+	}
+}
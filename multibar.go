@@ -0,0 +1,450 @@
+package progressbar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// Remove removes the bars (and their children, see Bar.NewChild()) from the multi bar: the bars below move up
+// to take their place and the screen lines freed at the end are cleared. Removed bars aren't drawn anymore.
+// This is safe to call while the other bars are updating.
+func (mb *MultiBar) Remove(bars ...*Bar) {
+	mb.out.Lock()
+	var all []*Bar
+	for _, b := range bars {
+		all = append(all, b)
+		all = append(all, b.descendants()...)
+	}
+	mb.out.Unlock()
+	for _, b := range all {
+		b.stopRenderer()
+	}
+	mb.out.Lock()
+	defer mb.out.Unlock()
+	removed := false
+	for _, b := range all {
+		removed = mb.remove(b) || removed
+	}
+	if removed {
+		mb.layout()
+	}
+}
+
+// remove removes the bar from the bars (and from its parent's children), returns false if it wasn't there.
+// Called with the lock held.
+func (mb *MultiBar) remove(bar *Bar) bool {
+	i := mb.position(bar)
+	if i < 0 {
+		return false
+	}
+	mb.Bars = append(mb.Bars[:i], mb.Bars[i+1:]...)
+	switch {
+	case bar == mb.total:
+		mb.total = nil
+	case !bar.branch:
+		// Its final counts stay in the total (a bar removed before its total is known counting as complete).
+		current, total := bar.Current(), bar.Total()
+		if total <= 0 {
+			total = current
+		}
+		mb.removedCurrent += current
+		mb.removedTotal += total
+		if s := atomic.LoadInt64(&bar.startNano); s != 0 && (mb.removedStart == 0 || s < mb.removedStart) {
+			mb.removedStart = s
+		}
+	}
+	if p := bar.parent; p != nil {
+		for k, c := range p.children {
+			if c == bar {
+				p.children = append(p.children[:k], p.children[k+1:]...)
+				break
+			}
+		}
+	}
+	bar.mb = nil
+	bar.index = 0
+	bar.hidden = false
+	bar.ended = true
+	return true
+}
+
+// position returns the position of the bar in the multi bar, -1 if not found.
+func (mb *MultiBar) position(bar *Bar) int {
+	for i, b := range mb.Bars {
+		if b == bar {
+			return i
+		}
+	}
+	return -1
+}
+
+// maxVisible returns the maximum number of bars on screen (0 for no limit), see Config.MaxVisibleBars.
+func (mb *MultiBar) maxVisible() int {
+	switch {
+	case mb.MaxVisibleBars > 0:
+		return mb.MaxVisibleBars
+	case mb.MaxVisibleBars < 0:
+		return 0
+	}
+	_, rows, ok := termSize(mb.out.out)
+	if !ok {
+		return 0
+	}
+	// Leave room for the trailer line and the line after the bars.
+	n := (rows - 2) / (1 + mb.ExtraLines)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Bar states, in order of priority to be shown when there are more bars than room on screen.
+const (
+	barTotal = iota // the total bar is always shown.
+	barRunning
+	barEnded
+	barPending
+)
+
+// state returns whether the bar is running (made some progress), ended or pending (not started yet).
+func (bar *Bar) state() int {
+	switch {
+	case bar.mb != nil && bar == bar.mb.total:
+		return barTotal
+	case bar.ended:
+		return barEnded
+	case bar.Current() > 0 || bar.latestPercent() > 0:
+		return barRunning
+	default:
+		return barPending
+	}
+}
+
+// assign decides which bars are on screen, when there are more than the maximum: running ones first, then the
+// finished ones and the pending ones (keeping the order), and computes their position. Returns true if anything
+// changed. Called with the lock held.
+func (mb *MultiBar) assign() bool {
+	limit := mb.maxVisible()
+	if limit <= 0 || len(mb.Bars) <= limit || mb.Bars[0].logMode() {
+		limit = len(mb.Bars)
+	}
+	show := make(map[*Bar]bool, limit)
+	for _, state := range []int{barTotal, barRunning, barEnded, barPending} {
+		for _, b := range mb.Bars {
+			if len(show) < limit && b.state() == state {
+				show[b] = true
+			}
+		}
+	}
+	changed := false
+	mul := 1 + mb.ExtraLines
+	mb.visible, mb.hidden = 0, 0
+	for _, b := range mb.Bars {
+		hidden := !show[b]
+		index := b.index
+		if hidden {
+			mb.hidden++
+		} else {
+			index = mul * mb.visible
+			mb.visible++
+		}
+		if hidden != b.hidden || index != b.index {
+			changed = true
+		}
+		b.hidden, b.index = hidden, index
+	}
+	return changed
+}
+
+// layout assigns the bars positions, makes room on screen if more lines are needed and redraws them.
+// Called with the lock held.
+func (mb *MultiBar) layout() {
+	mb.assign()
+	if len(mb.Bars) == 0 || mb.Bars[0].logMode() {
+		return
+	}
+	if last := mb.lastRow(); last > mb.reserved {
+		// Newlines (scrolling if needed) to make sure the lines exist, then back up to the first bar.
+		_, back := rowMoves(last)
+		_, _ = mb.out.out.Write([]byte("\r" + strings.Repeat("\n", last) + back))
+		mb.reserved = last
+	}
+	mb.relayout()
+}
+
+// lastRow returns the last screen row used by the multi bar, relative to the first bar.
+func (mb *MultiBar) lastRow() int {
+	if mb.visible == 0 {
+		return 0
+	}
+	last := (1 + mb.ExtraLines) * (mb.visible - 1)
+	if mb.hidden > 0 {
+		last++ // trailer line.
+	}
+	return last
+}
+
+// finished is called when a bar ended, to update the total bar and move it off screen if there are active
+// bars waiting to be shown. Called with the lock held.
+func (mb *MultiBar) finished(bar *Bar) {
+	if mb.total != nil && bar != mb.total {
+		mb.updateTotal()
+	}
+	if mb.hidden == 0 {
+		return
+	}
+	if bar.hidden {
+		mb.drawTrailer()
+		return
+	}
+	if mb.assign() {
+		mb.layout()
+	}
+}
+
+// relayout redraws the visible bars at their position (with their WriteAbove() message), the trailer
+// line if some bars are off screen, and clears the lines left after. Called with the lock held.
+func (mb *MultiBar) relayout() {
+	if len(mb.Bars) > 0 && mb.Bars[0].logMode() {
+		return
+	}
+	for _, b := range mb.Bars {
+		if b.hidden {
+			continue
+		}
+		for e := mb.ExtraLines; e >= 1; e-- {
+			text := ""
+			if e == 1 {
+				text = b.above
+			}
+			mb.writeRow(b.index-e, text)
+		}
+		mb.writeRow(b.index, "")
+		b.redraw()
+	}
+	// First line after the last bar (which is the first extra line of the next bar if any).
+	row := (1+mb.ExtraLines)*mb.visible - mb.ExtraLines
+	if mb.hidden > 0 {
+		mb.writeRow(row, mb.trailer())
+		row++
+	}
+	if row <= mb.reserved { // (moving further down would stop at the bottom of the screen)
+		to, back := rowMoves(row)
+		_, _ = mb.out.out.Write([]byte("\r" + to + ClearAfter + back))
+	}
+}
+
+// trailer returns the line summarizing the bars off screen: how many, how many are done (complete or
+// finished successfully, not the failed or aborted ones) and their aggregate progress.
+func (mb *MultiBar) trailer() string {
+	done := 0
+	total := 0.
+	for _, b := range mb.Bars {
+		if !b.hidden {
+			continue
+		}
+		p := b.latestPercent()
+		if b.status == FinishSuccess || isDone(p) {
+			done++
+		}
+		if p > 0 {
+			total += math.Min(p, 100)
+		}
+	}
+	return fmt.Sprintf("%s and %d more (%d done) %.1f%%", Ellipsis, mb.hidden, done, total/float64(mb.hidden))
+}
+
+// hiddenUpdate is called when an off screen bar progresses: it is brought on screen if it just started (and
+// there is room, e.g. from a finished bar), otherwise the trailer line is updated. Called with the lock held.
+func (mb *MultiBar) hiddenUpdate() {
+	if mb.assign() {
+		mb.layout()
+		return
+	}
+	mb.drawTrailer()
+}
+
+// drawTrailer updates the trailer line. Called with the lock held.
+func (mb *MultiBar) drawTrailer() {
+	mb.writeRow(mb.lastRow(), mb.trailer())
+}
+
+// writeRow replaces the content of the line at row (relative to the first bar, negative for the extra lines
+// above it) by text.
+func (mb *MultiBar) writeRow(row int, text string) {
+	to, back := rowMoves(row)
+	_, _ = mb.out.out.Write([]byte("\r" + to + text + "\033[K" + back))
+}
+
+// rowMoves returns the cursor movements to go from the first bar line to the given row and back.
+func rowMoves(row int) (to, back string) {
+	switch {
+	case row > 0:
+		return fmt.Sprintf("\033[%dB", row), fmt.Sprintf("\033[%dA", row)
+	case row < 0:
+		return fmt.Sprintf("\033[%dA", -row), fmt.Sprintf("\033[%dB", -row)
+	default:
+		return "", ""
+	}
+}
+
+// redraw draws the bar again, e.g. at its new position in a multi bar, including its final rendering
+// once ended (bars not updated yet stay blank). Called with the lock held.
+func (bar *Bar) redraw() {
+	switch {
+	case bar.ended:
+		bar.replaceLine(bar.finalLine)
+	case atomic.LoadInt64(&bar.startNano) != 0:
+		bar.draw(bar.latestPercent())
+	}
+}
+
+// Writer returns an io.Writer, safe for concurrent use with the bars updates, to print output above the multi
+// bar (for instance to pass to log.SetOutput() or a slog handler): the bars are erased, the complete lines written
+// are printed and the bars are redrawn below them. An incomplete last line is kept until its end is written
+// (or End() is called). Once the multi bar is ended, or in log mode, the output is just written as is.
+func (mb *MultiBar) Writer() io.Writer {
+	return multiBarWriter{mb}
+}
+
+type multiBarWriter struct {
+	mb *MultiBar
+}
+
+func (w multiBarWriter) Write(buf []byte) (int, error) {
+	mb := w.mb
+	mb.out.Lock()
+	defer mb.out.Unlock()
+	mb.logBuf = append(mb.logBuf, buf...)
+	i := bytes.LastIndexByte(mb.logBuf, '\n')
+	if i < 0 {
+		return len(buf), nil
+	}
+	err := mb.printAbove(mb.logBuf[:i+1])
+	mb.logBuf = append(mb.logBuf[:0], mb.logBuf[i+1:]...)
+	return len(buf), err
+}
+
+// printAbove prints the lines above the bars and redraws them below. Called with the lock held.
+func (mb *MultiBar) printAbove(lines []byte) error {
+	if mb.ended || len(mb.Bars) == 0 || mb.Bars[0].noAnsi() {
+		return mb.out.emit(lines)
+	}
+	// Clear from the first line of the block (the extra lines above the first bar) to the end of the screen,
+	// print the lines, make room for the block again (as in reservespace()) and redraw it.
+	to, _ := rowMoves(-mb.ExtraLines)
+	_, back := rowMoves(mb.reserved)
+	if _, err := mb.out.out.Write([]byte("\r" + to + ClearAfter)); err != nil {
+		return err
+	}
+	_, err := mb.out.out.Write(lines)
+	_, _ = mb.out.out.Write([]byte(strings.Repeat("\n", mb.ExtraLines+mb.reserved) + back))
+	mb.relayout()
+	return err
+}
+
+// TotalPosition is where the total bar is shown in a multi bar, see MultiBar.AddTotalBar().
+type TotalPosition int
+
+const (
+	// TotalBottom shows the total bar after the other bars.
+	TotalBottom TotalPosition = iota
+	// TotalTop shows the total bar before the other bars.
+	TotalTop
+)
+
+// AddTotalBar adds a bar showing the overall progress of the other bars of the multi bar: the sum of their
+// current and total counts (so weighted by bytes for instance, when they are AutoProgressReader/Writers, rather than
+// averaging percentages), with overall speed and ETA. It updates whenever any of the bars updates and is always on
+// screen. The amounts are shown in bytes or, when the multi bar Config.Unit is set, as counts (see CountExtra).
+// The total is unknown while one of the bars total is. Bars removed from the multi bar (Remove(), OnFinishClear,
+// CollapseOnEnd) keep being accounted for with their final counts.
+func (mb *MultiBar) AddTotalBar(prefix string, pos TotalPosition) *Bar {
+	cfg := mb.Config
+	cfg.Prefix = prefix
+	bar := cfg.NewBar()
+	if cfg.Unit != "" {
+		bar.Extra = bar.CountExtra
+		bar.Summary = bar.CountSummary
+	} else {
+		a := &AutoProgress{bar}
+		bar.Extra = a.Extra
+		bar.Summary = a.Summary
+	}
+	bar.SetTotal(0)
+	mb.Add(bar)
+	mb.out.Lock()
+	mb.total = bar
+	mb.totalTop = pos == TotalTop
+	mb.placeTotal()
+	mb.layout()
+	mb.updateTotal()
+	mb.out.Unlock()
+	mb.PrefixesAlign()
+	return bar
+}
+
+// placeTotal moves the total bar (if any) first or last in the bars. Called with the lock held.
+func (mb *MultiBar) placeTotal() {
+	if mb.total == nil {
+		return
+	}
+	i := mb.position(mb.total)
+	if i < 0 {
+		mb.total = nil // removed.
+		return
+	}
+	bars := append(mb.Bars[:i:i], mb.Bars[i+1:]...)
+	if mb.totalTop {
+		mb.Bars = append([]*Bar{mb.total}, bars...)
+	} else {
+		mb.Bars = append(bars, mb.total)
+	}
+}
+
+// updateTotal recomputes the total bar counts from the other bars (the leaves for tree bars), including the
+// removed ones, and draws it (subject to UpdateInterval). Called with the lock held.
+func (mb *MultiBar) updateTotal() {
+	t := mb.total
+	leaves := make([]*Bar, 0, len(mb.Bars))
+	for _, b := range mb.Bars {
+		if b != t && !b.branch {
+			leaves = append(leaves, b)
+		}
+	}
+	current, total, start := sumCounts(leaves)
+	current += mb.removedCurrent
+	if total >= 0 {
+		total += mb.removedTotal
+	}
+	if s := mb.removedStart; s != 0 && (start == 0 || s < start) {
+		start = s
+	}
+	atomic.StoreInt64(&t.current, current)
+	atomic.StoreInt64(&t.total, total)
+	if start != 0 {
+		atomic.StoreInt64(&t.startNano, start)
+	}
+	t.progress(t.countPercent())
+}
+
+// sumCounts returns the sum of the bars current and total counts (total -1 if one of them isn't known)
+// and the earliest start time.
+func sumCounts(bars []*Bar) (current, total, start int64) {
+	for _, b := range bars {
+		current += b.Current()
+		if bt := b.Total(); bt > 0 && total >= 0 {
+			total += bt
+		} else {
+			total = -1
+		}
+		if s := atomic.LoadInt64(&b.startNano); s != 0 && (start == 0 || s < start) {
+			start = s
+		}
+	}
+	return current, total, start
+}
@@ -0,0 +1,134 @@
+package progressbar_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"fortio.org/progressbar"
+)
+
+func TestTotalBarKeepsRemovedBars(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	cfg.UpdateInterval = 0
+	cfg.Unit = "items"
+	a, b := cfg.NewCountBar(10), cfg.NewCountBar(10)
+	mb := cfg.NewMultiBar(a, b)
+	total := mb.AddTotalBar("Total", progressbar.TotalBottom)
+	a.Add(10)
+	b.Add(5)
+	if c, tt := total.Current(), total.Total(); c != 15 || tt != 20 {
+		t.Errorf("total %d/%d, want 15/20", c, tt)
+	}
+	mb.Remove(a)
+	b.Add(1)
+	if c, tt := total.Current(), total.Total(); c != 16 || tt != 20 {
+		t.Errorf("after Remove total %d/%d, want 16/20", c, tt)
+	}
+	mb.End()
+}
+
+func TestTotalBarTreeCollapse(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	cfg.UpdateInterval = 0
+	cfg.Unit = "items"
+	cfg.DeriveFromChildren = true
+	cfg.CollapseOnEnd = true
+	mb := cfg.NewMultiBarPrefixes("build")
+	total := mb.AddTotalBar("Total", progressbar.TotalBottom)
+	stage := newChild(t, mb.Bars[0], "stage")
+	step := newChild(t, stage, "step")
+	step.SetTotal(4)
+	step.Add(4)
+	other := newChild(t, mb.Bars[0], "other")
+	other.SetTotal(4)
+	other.Add(1)
+	stage.End() // collapses (removes) step.
+	other.Add(1)
+	if c, tt := total.Current(), total.Total(); c != 6 || tt != 8 {
+		t.Errorf("after collapse total %d/%d, want 6/8", c, tt)
+	}
+	mb.End()
+}
+
+func newChild(t *testing.T, parent *progressbar.Bar, prefix string) *progressbar.Bar {
+	t.Helper()
+	child, err := parent.NewChild(prefix)
+	if err != nil {
+		t.Fatalf("NewChild(%q): %v", prefix, err)
+	}
+	return child
+}
+
+func TestNewChildStandalone(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	bar := cfg.NewBar()
+	defer bar.End()
+	child, err := bar.NewChild("child")
+	if !errors.Is(err, progressbar.ErrNoMultiBar) || child != nil {
+		t.Errorf("NewChild on a standalone bar = %v, %v; want nil, ErrNoMultiBar", child, err)
+	}
+}
+
+// In log mode each bar's final line is printed once, by its End() or with LogBlock by the multi bar's End().
+func TestLogModeFinalLines(t *testing.T) {
+	for _, block := range []bool{false, true} {
+		var buf bytes.Buffer
+		cfg := progressbar.DefaultConfig()
+		cfg.ScreenWriter = &buf
+		cfg.NoAnsi = true
+		cfg.UpdateInterval = 0
+		cfg.LogBlock = block
+		mb := cfg.NewMultiBarPrefixes("alpha", "beta")
+		mb.Bars[0].Progress(50)
+		mb.Bars[1].Progress(25)
+		mb.End()
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		for _, tst := range []struct{ prefix, final string }{{"alpha", "50.0%"}, {"beta", "25.0%"}} {
+			n := 0
+			for _, line := range lines {
+				if strings.HasPrefix(line, tst.prefix) && strings.HasSuffix(line, tst.final) {
+					n++
+				}
+			}
+			if n != 1 {
+				t.Errorf("LogBlock %v: %d %s %s lines, want 1 in:\n%s", block, n, tst.prefix, tst.final, buf.String())
+			}
+		}
+	}
+}
+
+// The trailer of the off screen bars only counts the complete or successful ones as done, with their actual progress.
+func TestTrailerDoneCount(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	cfg.UpdateInterval = 0
+	cfg.MaxVisibleBars = 1
+	mb := cfg.NewMultiBarPrefixes("a", "b", "c", "d", "e")
+	mb.Bars[0].Progress(50) // the one on screen.
+	mb.Bars[1].Progress(20)
+	mb.Bars[1].End() // ended early.
+	mb.Bars[2].Progress(100)
+	mb.Bars[3].Progress(30)
+	mb.Bars[3].Fail(errors.New("failed"))
+	mb.Bars[4].Progress(50)
+	mb.Bars[4].Succeed()
+	out := buf.String()
+	i := strings.LastIndex(out, progressbar.Ellipsis)
+	if i < 0 {
+		t.Fatalf("no trailer in %q", out)
+	}
+	want := progressbar.Ellipsis + " and 4 more (2 done) 50.0%"
+	if got := out[i : i+len(want)]; got != want {
+		t.Errorf("trailer %q, want %q", got, want)
+	}
+	mb.End()
+}
@@ -0,0 +1,183 @@
+package progressbar
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TaskbarState is the state of the progress shown in the terminal tab/taskbar, see Config.TaskbarProgress.
+// The values are the ones of the OSC 9;4 sequence.
+type TaskbarState int
+
+const (
+	// TaskbarNone removes the progress.
+	TaskbarNone TaskbarState = iota
+	// TaskbarNormal is a regular progress.
+	TaskbarNormal
+	// TaskbarError is a failed progress (usually shown in red).
+	TaskbarError
+	// TaskbarIndeterminate is a progress whose percentage isn't known (usually animated).
+	TaskbarIndeterminate
+	// TaskbarPaused is a paused (or aborted) progress (usually shown in yellow).
+	TaskbarPaused
+)
+
+const (
+	// Save and restore the window title (xterm title stack) so End() puts back the original one.
+	pushTitle = "\033[22;0t"
+	popTitle  = "\033[23;0t"
+)
+
+// oscProgress is the last taskbar progress and title sent, to only send changes and reset them at the end.
+type oscProgress struct {
+	state   TaskbarState
+	percent int
+	title   string
+	active  bool
+}
+
+// appendUpdate appends the sequences for the new state, percentage and title, if they changed.
+func (o *oscProgress) appendUpdate(buf []byte, cfg *Config, state TaskbarState, percent int, title string) []byte {
+	if cfg.TaskbarProgress && (!o.active || state != o.state || percent != o.percent) {
+		buf = append(buf, fmt.Sprintf("\033]9;4;%d;%d\a", state, percent)...)
+	}
+	if cfg.TitleProgress && (!o.active || title != o.title) {
+		if !o.active {
+			buf = append(buf, pushTitle...)
+		}
+		buf = append(buf, "\033]2;"+title+"\a"...)
+	}
+	o.state, o.percent, o.title, o.active = state, percent, title, true
+	return buf
+}
+
+// appendReset appends the sequences removing the progress and restoring the title, if any were sent.
+func (o *oscProgress) appendReset(buf []byte, cfg *Config) []byte {
+	if !o.active {
+		return buf
+	}
+	if cfg.TaskbarProgress {
+		buf = append(buf, "\033]9;4;0;0\a"...)
+	}
+	if cfg.TitleProgress {
+		buf = append(buf, popTitle...)
+	}
+	o.active = false
+	return buf
+}
+
+// oscEnabled returns true when the progress is mirrored in the taskbar or title.
+func (cfg *Config) oscEnabled() bool {
+	return cfg.TaskbarProgress || cfg.TitleProgress
+}
+
+// oscTitle returns the window title for the state and percentage, e.g. "45% Downloading".
+func oscTitle(state TaskbarState, progressPercent float64, prefix string) string {
+	title := StripAnsi(strings.TrimSpace(prefix))
+	if state != TaskbarIndeterminate {
+		title = strings.TrimSpace(fmt.Sprintf("%d%% %s", oscPercent(progressPercent), title))
+	}
+	switch state {
+	case TaskbarError:
+		title = FailureSymbol + title
+	case TaskbarPaused:
+		title += " (paused)"
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1 // no control characters (which would end the sequence) in the title.
+		}
+		return r
+	}, title)
+}
+
+// oscPercent returns the percentage as an integer between 0 and 100.
+func oscPercent(progressPercent float64) int {
+	switch {
+	case progressPercent <= 0 || math.IsNaN(progressPercent):
+		return 0
+	case progressPercent >= 100:
+		return 100
+	default:
+		return int(progressPercent)
+	}
+}
+
+// taskbarState returns the taskbar state of the bar.
+func (bar *Bar) taskbarState(progressPercent float64) TaskbarState {
+	switch {
+	case bar.status == FinishFailure:
+		return TaskbarError
+	case bar.paused || bar.status == FinishAborted:
+		return TaskbarPaused
+	case progressPercent < 0:
+		return TaskbarIndeterminate
+	default:
+		return TaskbarNormal
+	}
+}
+
+// SetPaused marks the bar as paused (or not anymore) in the taskbar progress and title, see Config.TaskbarProgress.
+// This is thread safe / acquires a shared lock to avoid issues on the output.
+func (bar *Bar) SetPaused(paused bool) {
+	bar.out.Lock()
+	bar.paused = paused
+	bar.draw(bar.latestPercent())
+	bar.out.Unlock()
+}
+
+// appendOSC appends the taskbar progress and title sequences for the bar, or its multi bar's aggregate,
+// when they changed. Called with the lock held.
+func (bar *Bar) appendOSC(buf []byte, progressPercent float64) []byte {
+	switch {
+	case bar.noAnsi():
+		return buf
+	case bar.mb != nil:
+		return bar.mb.appendOSC(buf)
+	case !bar.oscEnabled():
+		return buf
+	}
+	state := bar.taskbarState(progressPercent)
+	return bar.osc.appendUpdate(buf, &bar.Config, state, oscPercent(progressPercent),
+		oscTitle(state, progressPercent, bar.Prefix))
+}
+
+// appendOSC appends the taskbar progress and title sequences for the aggregate of the bars: the total bar
+// progress if any, otherwise the average of the bars (the leaves for tree bars). The state is the error one if a
+// bar failed, paused if one is paused and indeterminate while no percentage is known. Called with the lock held.
+func (mb *MultiBar) appendOSC(buf []byte) []byte {
+	if !mb.oscEnabled() {
+		return buf
+	}
+	state := TaskbarNormal
+	sum, known := 0., 0
+	for _, b := range mb.Bars {
+		switch b.taskbarState(b.latestPercent()) {
+		case TaskbarError:
+			state = TaskbarError
+		case TaskbarPaused:
+			if state != TaskbarError {
+				state = TaskbarPaused
+			}
+		}
+		if b == mb.total || b.branch {
+			continue
+		}
+		if p := b.latestPercent(); p >= 0 {
+			sum += math.Min(p, 100)
+			known++
+		}
+	}
+	p := -1.
+	switch {
+	case mb.total != nil:
+		p = mb.total.latestPercent()
+	case known > 0:
+		p = sum / float64(known)
+	}
+	if p < 0 && state == TaskbarNormal {
+		state = TaskbarIndeterminate
+	}
+	return mb.osc.appendUpdate(buf, &mb.Config, state, oscPercent(p), oscTitle(state, p, mb.Prefix))
+}
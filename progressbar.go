@@ -10,12 +10,16 @@
 package progressbar
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -51,13 +55,40 @@ var (
 // Config is the common configuration for the progress bar and multi bars.
 type Config struct {
 	// Width of the progress bar in characters (0 will use DefaultWidth).
+	// With AutoWidth this is the maximum width.
 	Width int
+	// AutoWidth to fit the line in the terminal width (when the ScreenWriter is a terminal, on linux):
+	// the bar part is shrunk first and then the text is elided. Multi bars are also redrawn when
+	// the terminal is resized.
+	AutoWidth bool
 	// UseColors to use colors in the progress bar (default is true).
 	UseColors bool
 	// Which color to use for the bar (default is green) if UseColors is true.
+	// Either one of the predefined 16 colors ones (GreenBar, RedBar,...) or for instance from BarColor()
+	// for RGB or 256 colors.
 	Color string
+	// Gradient, when set, colors the bar according to the percentage instead of Color: the whole bar takes the
+	// color of the last stop reached (for instance red then yellow then green), or with GradientBlend each filled
+	// cell gets the blended color of its position along the bar. Background is the color of the empty part
+	// (nil for DefaultBackground). RGB colors are downgraded to the nearest color the terminal supports
+	// according to ColorMode (ColorModeAuto, the default, detects it from COLORTERM/TERM).
+	Gradient      []ColorStop
+	GradientBlend bool
+	Background    *RGB
+	ColorMode     ColorMode
+	// Theme for the bar glyphs (fill, empty, fractional steps, caps) and optional colors,
+	// nil for DefaultTheme. See also ASCIITheme, BrailleTheme, ShadedTheme and DotsTheme.
+	// Themes with glyphs not 1 column wide are replaced by DefaultTheme (see Theme.Validate()).
+	Theme *Theme
 	// Spinner to also show a spinner in front of the progress bar.
 	Spinner bool
+	// SpinnerStyle is the spinner animation (nil for SpinnerDots), see SpinnerStyles for the built-in ones.
+	SpinnerStyle *SpinnerStyle
+	// Indeterminate to show a segment bouncing across the bar while the progress isn't known (negative
+	// percentage, e.g. unknown total) instead of no bar at all. It turns into the regular bar as soon as the
+	// progress is known (e.g. SetTotal() once the length arrives). Animated by the background renderer
+	// (see Start()) or at each update.
+	Indeterminate bool
 	// Prefix to show before the progress bar (can be updated while running using UpdatePrefix() or through Extra()).
 	Prefix string
 	// Suffix to show after the percentage information on the bar (can be updated while running using UpdateSuffix()).
@@ -67,29 +98,140 @@ type Config struct {
 	// Minimum duration between updates (0 to update every time).
 	UpdateInterval time.Duration
 	// Option to avoid all ANSI sequences (useful for non terminal output/test/go playground),
-	// Implies UseColors=false. Not applicable to multi bars (which need ANSI cursor moves to update each bar).
+	// Implies UseColors=false. Multi bars, which need ANSI cursor moves to update each bar in place,
+	// use the log mode below instead.
 	NoAnsi bool
+	// When the ScreenWriter is a file that isn't a terminal (CI, output redirected to a file...), the bar
+	// automatically switches to a log friendly mode printing a complete line every LogPercentStep percent
+	// (0 for DefaultLogPercentStep) and at least every LogInterval (0 for DefaultLogInterval), but no more than
+	// once per UpdateInterval, plus the final state on End(). Set ForceTTY to keep the in place ANSI updates regardless.
+	// Multi bars in log mode print each bar's lines (with its prefix) the same way, or with LogBlock a block
+	// with the line of every bar at most every LogInterval, and a final line per bar on MultiBar.End().
+	LogPercentStep float64
+	LogInterval    time.Duration
+	ForceTTY       bool
+	LogBlock       bool
+	// JSONEvents to write newline delimited JSON events (see JSONEvent) to the ScreenWriter instead of drawing the
+	// bar, for tools driven by other programs (IDE plugins, web UIs...): "start", "progress" (at most every
+	// UpdateInterval), "message" (WriteAbove() and Writer() lines) and "finish" with the status. Also applies to
+	// multi bars, each of their bars having its own ID (see Bar.ID()).
+	JSONEvents bool
+	// TaskbarProgress mirrors the progress in the terminal tab/taskbar (OSC 9;4, supported by Windows Terminal, ConEmu,
+	// WezTerm, Ghostty...) and TitleProgress in the window title (e.g. "45% Downloading"), to see it while the window
+	// is in the background: indeterminate while the percentage isn't known, error once failed and paused (see
+	// SetPaused()). Both are reset on End(). Multi bars show the aggregate of their bars. Not applicable without
+	// ANSI (NoAnsi, log mode).
+	TaskbarProgress bool
+	TitleProgress   bool
 	// Underlying specific destination writer for the screen/terminal.
-	// (defaults when nil will use the shared screen writer based on os.Stderr).
+	// (defaults when nil will use the shared screen writer based on os.Stderr, or its own for JSONEvents).
 	// Sets to os.Stdout or os.Stderr or any other Writer (that ends up outputting to ANSI aware terminal) to use
 	// this with your existing code if the os.Stderr default global shared screen writer doesn't work for you.
 	ScreenWriter io.Writer
 	// Extra lines between each bar for multibars.
 	ExtraLines int
+	// MaxVisibleBars is the maximum number of bars of a multi bar shown at once. When there are more bars, the
+	// active ones are shown first (finished ones are moved off screen) and a last line shows how many more there are
+	// and their aggregate progress. 0 derives it from the terminal height (when known, on linux), -1 for no limit.
+	MaxVisibleBars int
+	// For tree bars (see NewChild()): DeriveFromChildren computes the progress of a bar from its children (sum of
+	// their counts when they all have a total, average of their percentages otherwise) and CollapseOnEnd removes
+	// the children of a bar from the screen once it ends.
+	DeriveFromChildren bool
+	CollapseOnEnd      bool
+	// Number of frames per second drawn by the background renderer when started with Start()
+	// (0 will use DefaultFPS).
+	FPS int
+	// Widgets composing the progress bar line, in order. Defaults to DefaultWidgets() when nil
+	// which is Prefix, spinner, bar, percentage, Suffix and Extra.
+	Widgets []Widget
+	// Unit name of the counts shown by CountExtra (defaults to "items").
+	Unit string
+	// OnFinish is what happens to the bar line once ended (End() or Finish()): kept as is (OnFinishKeep, the default),
+	// erased (OnFinishClear) or replaced by a one line summary (OnFinishSummary) e.g.
+	// "✓ file.tar.gz 120.000 Mb in 3.2s (37.500 Mb/s)". Bars of a multi bar are cleared or summarized in place
+	// while the others keep updating. In log mode the final line is always printed (as the summary with OnFinishSummary).
+	OnFinish FinishAction
 }
 
 type Bar struct {
+	// Current and total counts (for Add()/SetTotal() and the counter, speed and ETA widgets).
+	// Accessed atomically, first in the struct for 64 bits alignment on 32 bits platforms.
+	current int64
+	total   int64
+	// Time of the first progress update or first count added, in unix nanoseconds (atomic).
+	startNano int64
+	// Current/last progress percentage as float64 bits (atomic, see Progress() and Redraw()).
+	percentBits uint64
+	// Set (atomically) once the bar is count based (SetTotal()/Add() used), the percentage is then derived from the counts.
+	counting int32
+	// Set (atomically) while the background renderer is running (see Start()), and when there is a new state to draw.
+	rendering int32
+	dirty     int32
+	// Set (atomically) once drawn as lines (log mode) or JSONEvents, which are never erased by other output.
+	printing int32
+	// Last update time in unix nanoseconds (atomic, used to skip updates coming before UpdateInterval has elapsed
+	// without taking the lock).
+	lastUpdate int64
+	// Hot path throttling (atomic, see skipUpdateFast()): time of the last clock read in unix nanoseconds and the
+	// range of positions (count or percentage, as float64 bits) within which the clock isn't read again.
+	lastCheck int64
+	checkFrom uint64
+	checkTo   uint64
+	// Identifier, see ID().
+	id int64
+	// JSONEvents state: whether the start event was written and the percentage and count of the last progress event.
+	jsonCurrent int64
+	jsonPercent float64
+	jsonStarted bool
 	Config
 	// Extra string to show after the progress bar. Keep nil for no extra.
 	Extra func(cfg *Bar, progressPercent float64) string
-	// Internal last update time (used to skip updates coming before UpdateInterval has elapsed).
-	lastUpdate time.Time
+	// Summary returns the details of the one line summary shown with OnFinishSummary, after the status symbol
+	// and prefix. Set by NewAutoReader/Writer (amount of bytes) and NewCountBar (counts), nil for just the elapsed time.
+	Summary func(bar *Bar) string
+	// Estimator of the rate used for the speed and remaining time (nil for the whole transfer average).
+	// Set it before the first update, e.g. bar.Estimator = progressbar.NewEWMAEstimator(0).
+	Estimator Estimator
 	// Writer to write to.
 	out *writer
 	// Index for multi bar (to move the cursor up/down).
 	index int
-	// Current/last progress percentage (to refresh multi bars upon resize of prefix).
-	percent float64
+	// Background renderer, when started.
+	renderer *renderer
+	// Width of the bar part for the current rendering (Width or less with AutoWidth).
+	barWidth int
+	// Last Theme validated and whether it's valid (see theme()).
+	checkedTheme *Theme
+	themeValid   bool
+	// Start time of the spinner and indeterminate bar animations (each bar has its own phase).
+	spinnerStart       time.Time
+	indeterminateStart time.Time
+	// Log mode state: time, percentage and count of the last printed line, and whether there are unprinted
+	// updates (besides the ones skipped before reaching logDraw(), see UpdateInterval).
+	lastLogTime    time.Time
+	lastLogPercent float64
+	lastLogCurrent int64
+	logPending     bool
+	// Final status and message (see Finish()), set once ended (End() was called) and the final rendering
+	// (to redraw it when multi bars are rearranged).
+	status       FinishStatus
+	finalMessage string
+	ended        bool
+	finalLine    string
+	// Multi bar the bar is part of (nil for single bars), last WriteAbove() message and whether the bar
+	// is currently off screen (see Config.MaxVisibleBars).
+	mb     *MultiBar
+	above  string
+	hidden bool
+	// Tree bars (see NewChild()), branch is set once the bar had children (it then isn't counted in the total bar).
+	parent   *Bar
+	children []*Bar
+	depth    int
+	branch   bool
+	// Taskbar progress and title last sent (see Config.TaskbarProgress) and whether the bar is paused.
+	osc    oscProgress
+	paused bool
 }
 
 // UpdatePrefix changes the prefix while the progress bar is running.
@@ -113,79 +255,195 @@ func (bar *Bar) UpdateSuffix(s string) {
 // Use MoveCursorUp to move up to update other lines as needed or use Writer()
 // to write output without mixing with a progress bar.
 // This is thread safe / acquires a shared lock to avoid issues on the output.
-// Of note it will work best if every output to the Writer() ends with a \n.
 // The bar state must be obtained from NewBar() or cfg.NewBar() to setup the shared lock.
+// When the background renderer is running (see Start()), this only records the new percentage.
 func (bar *Bar) Progress(progressPercent float64) {
-	isDone := isDone(progressPercent)
+	atomic.StoreUint64(&bar.percentBits, math.Float64bits(progressPercent))
+	if atomic.LoadInt32(&bar.rendering) != 0 {
+		setFlag(&bar.dirty)
+		return
+	}
+	if bar.skipUpdateFast(isDone(progressPercent), progressPercent) {
+		return
+	}
 	bar.out.Lock()
-	defer bar.out.Unlock()
-	bar.percent = progressPercent
-	// Skip if last write was too recent and we're not done and nothing else was written in between.
-	if bar.UpdateInterval > 0 && !isDone && bar.out.needErase {
-		now := time.Now()
-		if now.Sub(bar.lastUpdate) < bar.UpdateInterval {
-			return
-		}
-		bar.lastUpdate = now
-	}
-	barStr := ""
-	progressPercentStr := ""
-	if progressPercent >= 0 && progressPercent <= 100 { //nolint:nestif // it's not that bad here
-		width := float64(bar.Width)
-		if width == 0 {
-			width = DefaultWidth
-		}
-		count := int(8*width*progressPercent/100. + 0.5)
-		fullCount := count / 8
-		remainder := count % 8
-		spaceCount := int(width) - fullCount - 1
-		if remainder == 0 {
-			spaceCount++
-		}
-		color := bar.Color
-		reset := Reset
-		if !bar.UseColors || bar.NoAnsi {
-			color = "◅" // "◢"
-			reset = "▻" // "◣"
-		}
-		barStr = color + strings.Repeat(Full, fullCount) + FractionalBlocks[remainder] + strings.Repeat(Space, spaceCount) + reset
-		if !bar.NoPercent {
-			progressPercentStr = fmt.Sprintf(" %.1f%%", progressPercent)
-		}
+	bar.progress(progressPercent)
+	bar.out.Unlock()
+}
+
+// progress is the implementation of Progress, called with the output lock held.
+func (bar *Bar) progress(progressPercent float64) {
+	now := time.Now().UnixNano()
+	if bar.skipUpdate(progressPercent, now) {
+		return
 	}
-	spinner := ""
-	if bar.Spinner {
-		spinner = SpinnerChars[bar.out.count]
-		bar.out.count = (bar.out.count + 1) % len(SpinnerChars)
-		if isDone {
-			spinner = DoneSpinner
-		}
+	if bar.UpdateInterval > 0 {
+		atomic.StoreInt64(&bar.lastUpdate, now)
+	}
+	bar.draw(progressPercent)
+}
+
+// skipUpdate returns true if the last write was too recent and we're not done and nothing else was written
+// in between. Lock free so it can be checked before acquiring the lock on hot paths (and then again with the lock).
+func (bar *Bar) skipUpdate(progressPercent float64, now int64) bool {
+	return bar.throttled(progressPercent) && now-atomic.LoadInt64(&bar.lastUpdate) < int64(bar.UpdateInterval)
+}
+
+// throttled returns true when updates are subject to UpdateInterval: we're not done and nothing else was
+// written since the last draw (always the case in log mode).
+func (bar *Bar) throttled(progressPercent float64) bool {
+	return !isDone(progressPercent) && bar.throttling()
+}
+
+// throttling is throttled() regardless of the progress.
+func (bar *Bar) throttling() bool {
+	return bar.UpdateInterval > 0 &&
+		(atomic.LoadInt32(&bar.out.needErase) != 0 || atomic.LoadInt32(&bar.printing) != 0)
+}
+
+// skipUpdateFast is skipUpdate() for the hot paths (Add(), Progress()): reading the clock is most of the cost
+// of an update that isn't drawn, so it's only read again once the position (the count, or the percentage for
+// Progress()) leaves the range it's expected to cover in a quarter of UpdateInterval at the rate measured between
+// the last clock reads. The calls within that range are skipped with just atomic loads (no shared writes).
+func (bar *Bar) skipUpdateFast(done bool, pos float64) bool {
+	if done || !bar.throttling() {
+		return false
+	}
+	if math.Float64frombits(atomic.LoadUint64(&bar.checkFrom)) < pos &&
+		pos < math.Float64frombits(atomic.LoadUint64(&bar.checkTo)) {
+		return true
+	}
+	return bar.checkClock(pos)
+}
+
+// checkClock is skipUpdateFast() when the position is out of the range: reads the clock, sets the next range
+// and returns true if the last draw is more recent than UpdateInterval.
+func (bar *Bar) checkClock(pos float64) bool {
+	now := time.Now().UnixNano()
+	from := math.Float64frombits(atomic.LoadUint64(&bar.checkFrom))
+	to := math.Float64frombits(atomic.LoadUint64(&bar.checkTo))
+	window := 0. // slow (or backward) progress: check each call.
+	sinceCheck := now - atomic.SwapInt64(&bar.lastCheck, now)
+	if moved := pos - from; moved > 0 && sinceCheck <= int64(bar.UpdateInterval) {
+		// Growing at most 2x at a time, so a burst of calls doesn't delay the next redraws for long.
+		window = math.Min(moved*float64(bar.UpdateInterval)/4/float64(sinceCheck), 2*(to-from)+moved)
+	}
+	atomic.StoreUint64(&bar.checkFrom, math.Float64bits(pos))
+	atomic.StoreUint64(&bar.checkTo, math.Float64bits(pos+window))
+	return now-atomic.LoadInt64(&bar.lastUpdate) < int64(bar.UpdateInterval)
+}
+
+// draw renders the bar unconditionally, called with the output lock held.
+func (bar *Bar) draw(progressPercent float64) {
+	if bar.ended {
+		return // keep the final rendering.
+	}
+	if bar.mb != nil && bar.mb.total != nil && bar.mb.total != bar {
+		bar.mb.updateTotal()
+	}
+	if bar.parent != nil && bar.parent.DeriveFromChildren {
+		bar.parent.deriveFromChildren()
+	}
+	if bar.hidden {
+		bar.mb.hiddenUpdate()
+		return
 	}
-	extra := ""
-	if bar.Extra != nil {
-		extra = bar.Extra(bar, progressPercent)
+	atomic.CompareAndSwapInt64(&bar.startNano, 0, time.Now().UnixNano())
+	if bar.Estimator != nil {
+		bar.Estimator.Sample(bar.elapsed(), bar.Current())
+	}
+	switch {
+	case bar.JSONEvents:
+		setFlag(&bar.printing)
+		bar.jsonDraw(progressPercent)
+		return
+	case bar.logMode():
+		setFlag(&bar.printing)
+		bar.logDraw(progressPercent, false)
+		return
 	}
 	bar.out.buf = bar.out.buf[:0]
 	bar.out.buf = append(bar.out.buf, bar.indexBasedMoveDown()...) // does \r in single bar mode.
-	bar.out.buf = append(bar.out.buf, bar.Prefix...)
-	bar.out.buf = append(bar.out.buf, spinner...)
-	bar.out.buf = append(bar.out.buf, barStr...)
-	bar.out.buf = append(bar.out.buf, progressPercentStr...)
-	bar.out.buf = append(bar.out.buf, bar.Suffix...)
-	bar.out.buf = append(bar.out.buf, extra...)
+	bar.out.buf = bar.render(bar.out.buf, progressPercent)
+	bar.out.buf = bar.appendOSC(bar.out.buf, progressPercent)
 	bar.out.buf = append(bar.out.buf, bar.indexBasedMoveUp()...)
 	// bar.out.buf = append(bar.out.buf, '\n') // Uncomment to debug/see all the incremental updates.
 	_, _ = bar.out.out.Write(bar.out.buf)
 	bar.out.buf = bar.out.buf[:0]
-	bar.out.needErase = true
-	bar.out.noAnsi = bar.NoAnsi
+	atomic.StoreInt32(&bar.out.needErase, 1)
+	bar.out.noAnsi = bar.noAnsi()
+}
+
+// render appends the bar line (without any cursor movement) to buf.
+// With AutoWidth, the bar part is shrunk first to fit the terminal width and then the text is elided.
+func (bar *Bar) render(buf []byte, progressPercent float64) []byte {
+	widgets := bar.Widgets
+	if widgets == nil {
+		widgets = DefaultWidgets()
+	}
+	width := bar.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	bar.barWidth = width
+	cols := 0
+	if bar.AutoWidth {
+		if c, _, ok := termSize(bar.out.out); ok {
+			cols = c - 1 // not using the last column to avoid auto wrap.
+		}
+	}
+	start := len(buf)
+	if cols > 0 {
+		// Measure the rest of the line with an empty bar to know how much room is left for the bar.
+		bar.barWidth = 0
+		buf = renderWidgets(buf, widgets, bar, progressPercent)
+		avail := cols - DisplayWidth(string(buf[start:]))
+		buf = buf[:start]
+		bar.barWidth = width
+		switch {
+		case avail < 0:
+			bar.barWidth = 0
+		case avail < width:
+			bar.barWidth = avail
+		}
+	}
+	buf = renderWidgets(buf, widgets, bar, progressPercent)
+	if cols > 0 {
+		if line := string(buf[start:]); DisplayWidth(line) > cols {
+			line = Truncate(line, cols)
+			buf = append(buf[:start], line...)
+			if !bar.noAnsi() {
+				buf = append(buf, Reset...)
+			}
+		}
+		if !bar.noAnsi() {
+			buf = append(buf, "\033[K"...) // clear what's left from a longer previous line.
+		}
+	}
+	return buf
+}
+
+func renderWidgets(buf []byte, widgets []Widget, bar *Bar, progressPercent float64) []byte {
+	for _, w := range widgets {
+		buf = w.Render(buf, bar, progressPercent)
+	}
+	return buf
 }
 
 // Redraw force the redraw the progress bar with last known percentage.
 func (bar *Bar) Redraw() {
-	var zero time.Time
-	bar.lastUpdate = zero
-	bar.Progress(bar.percent)
+	bar.out.Lock()
+	bar.draw(bar.latestPercent())
+	bar.out.Unlock()
+}
+
+// latestPercent returns the last percentage passed to Progress() or derived from the counts
+// for count based bars.
+func (bar *Bar) latestPercent() float64 {
+	if atomic.LoadInt32(&bar.counting) != 0 {
+		return bar.countPercent()
+	}
+	return math.Float64frombits(atomic.LoadUint64(&bar.percentBits))
 }
 
 // Approximate check if the progress is done (percent > 99.999).
@@ -193,18 +451,25 @@ func isDone(percent float64) bool {
 	return percent > 99.999
 }
 
+// spinnerCount is the frame of the standalone Spinner(), protected by the screenWriter lock.
+var spinnerCount int
+
 // Spinner is a standalone spinner when the total or progress toward 100% isn't known.
 // (but a progressbar with -1 total or with negative % progress does that too).
 func Spinner() {
 	screenWriter.Lock()
-	fmt.Fprintf(screenWriter, "\r%s", SpinnerChars[screenWriter.count])
-	screenWriter.count = (screenWriter.count + 1) % len(SpinnerChars)
+	fmt.Fprintf(screenWriter, "\r%s", SpinnerChars[spinnerCount])
+	spinnerCount = (spinnerCount + 1) % len(SpinnerChars)
 	screenWriter.Unlock()
 }
 
 // MoveCursorUp moves the cursor up n lines and clears that line.
-// If NoAnsi is configured, this just issue a new line.
+// If NoAnsi is configured, this just issue a new line (and does nothing in log mode,
+// when the output isn't a terminal, as lines are already complete).
 func (bar *Bar) MoveCursorUp(n int) {
+	if bar.logMode() {
+		return
+	}
 	if bar.NoAnsi {
 		fmt.Fprintf(bar.out.out, "\n")
 		return
@@ -217,25 +482,34 @@ func (bar *Bar) MoveCursorUp(n int) {
 }
 
 // WriteAbove is for multibars with extra lines, writes (1 line) above the bar.
+// In log mode (output not a terminal), the message is just printed on its own line.
 func (bar *Bar) WriteAbove(msg string) {
 	bar.out.Lock()
-	if bar.index > 0 {
+	bar.above = msg
+	switch {
+	case bar.JSONEvents:
+		bar.jsonMessage(msg)
+	case bar.logMode():
+		fmt.Fprintf(bar.out.out, "%s\n", msg)
+	case bar.hidden:
+		// shown when the bar becomes visible.
+	case bar.index > 0:
 		fmt.Fprintf(bar.out.out, "\r\033[%dB%s\n%s", bar.index-1, msg, bar.indexBasedMoveUp())
-	} else {
+	default:
 		fmt.Fprintf(bar.out.out, "\r\033[1A%s\n", msg)
 	}
 	bar.out.Unlock()
 }
 
 func (bar *Bar) indexBasedMoveUp() []byte {
-	if bar.index <= 0 || bar.NoAnsi {
+	if bar.index <= 0 || bar.noAnsi() {
 		return nil
 	}
 	return []byte(fmt.Sprintf("\033[%dA", bar.index))
 }
 
 func (bar *Bar) indexBasedMoveDown() []byte {
-	if bar.index <= 0 || bar.NoAnsi {
+	if bar.index <= 0 || bar.noAnsi() {
 		return []byte{'\r'}
 	}
 	return []byte(fmt.Sprintf("\r\033[%dB", bar.index))
@@ -245,34 +519,94 @@ type writer struct {
 	sync.Mutex
 	out       io.Writer
 	buf       []byte
-	count     int
-	needErase bool
+	needErase int32 // atomic, set when a progress bar was drawn and should be erased before other output.
 	noAnsi    bool
+	notTTY    bool // the destination is a file which isn't a terminal (see Config.ForceTTY).
+	json      bool // writes are turned into message events (see Config.JSONEvents).
+	// Incomplete last line written to Write(), and the timer to flush it after PartialLineTimeout.
+	partial []byte
+	flush   *time.Timer
+}
+
+// PartialLineTimeout is how long an incomplete line written to Bar.Writer() is kept, waiting for its end,
+// before being printed anyway (on its own line).
+const PartialLineTimeout = 500 * time.Millisecond
+
+func newWriter(out io.Writer, noAnsi bool) *writer {
+	return &writer{out: out, buf: make([]byte, 0, ExpectedMaxLength), noAnsi: noAnsi, notTTY: !isTerminal(out)}
 }
 
+// Write buffers the incomplete last line so the bar isn't redrawn in the middle of it, and writes the complete
+// lines, erasing the progress bar first.
 func (w *writer) Write(buf []byte) (n int, err error) {
 	w.Lock()
-	if w.needErase {
+	defer w.Unlock()
+	w.partial = append(w.partial, buf...)
+	if i := bytes.LastIndexByte(w.partial, '\n'); i >= 0 {
+		err = w.emit(w.partial[:i+1])
+		w.partial = append(w.partial[:0], w.partial[i+1:]...)
+		w.stopFlush() // the timeout starts again for the next incomplete line.
+	}
+	if len(w.partial) > 0 && w.flush == nil {
+		var t *time.Timer
+		t = time.AfterFunc(PartialLineTimeout, func() {
+			w.Lock()
+			if w.flush == t { // not stopped (and replaced) while waiting for the lock.
+				_ = w.flushPartial()
+			}
+			w.Unlock()
+		})
+		w.flush = t
+	}
+	return len(buf), err
+}
+
+// stopFlush stops the timer flushing the incomplete line, if any. Called with the lock held.
+func (w *writer) stopFlush() {
+	if w.flush != nil {
+		w.flush.Stop()
+		w.flush = nil
+	}
+}
+
+// emit erases the progress bar, if drawn, and writes the lines. Called with the lock held.
+func (w *writer) emit(lines []byte) error {
+	if w.json {
+		w.writeJSONLines(lines)
+		return nil
+	}
+	if atomic.LoadInt32(&w.needErase) != 0 {
 		if w.noAnsi {
 			_, _ = w.out.Write([]byte("\r")) // just carriage return and pray it's enough
 		} else {
 			_, _ = w.out.Write([]byte("\r\033[K")) // erase current progress bar line
 		}
-		w.needErase = false
+		atomic.StoreInt32(&w.needErase, 0)
 	}
-	n, err = w.out.Write(buf)
-	w.Unlock()
-	return
+	_, err := w.out.Write(lines)
+	return err
+}
+
+// flushPartial writes the incomplete last line, if any, terminating it (e.g. on End()). Called with the lock held.
+func (w *writer) flushPartial() error {
+	w.stopFlush()
+	if len(w.partial) == 0 {
+		return nil
+	}
+	err := w.emit(append(w.partial, '\n'))
+	w.partial = w.partial[:0]
+	return err
 }
 
 // Global write with lock and reused buffer.
 // Outside of testing there is generally only 1 valid output for ansi progress bar:
 // os.Stdout or os.Stderr.
-var screenWriter = &writer{out: os.Stderr, buf: make([]byte, 0, ExpectedMaxLength)}
+var screenWriter = newWriter(os.Stderr, false)
 
 // Writer returns the io.Writer that can be safely used concurrently with associated with the progress bar.
 // Any writes will clear the current line/progress bar and write the new content, and
-// then rewrite the progress bar at the next update.
+// then rewrite the progress bar at the next update. Incomplete lines are buffered until their end is
+// written, the bar End()s or PartialLineTimeout elapses, so the bar is never drawn in the middle of a line.
 func (bar *Bar) Writer() io.Writer {
 	return bar.out
 }
@@ -314,52 +648,57 @@ func HumanDuration(d time.Duration) string {
 // AutoProgress is base progress bar for auto reader/writers.
 type AutoProgress struct {
 	*Bar
-	total   int64
-	current int64
-	start   time.Time
 }
 
+// Update adds n bytes to the current count and updates the progress bar.
 func (a *AutoProgress) Update(n int) {
-	if a.current == 0 {
-		a.start = time.Now()
+	if n == 0 && a.Current() == 0 && a.Total() <= 0 {
+		return
 	}
-	a.current += int64(n)
-	if a.current > 0 || a.total > 0 {
-		a.Progress(float64(a.current) * 100. / float64(a.total))
+	a.Add(int64(n))
+}
+
+// Summary provides the details of the summary line (see Config.OnFinish): amount transferred, time and average speed.
+func (a *AutoProgress) Summary(_ *Bar) string {
+	return fmt.Sprintf("%s in %s (%s/s)", HumanBytes(a.Current()), HumanDuration(a.elapsed()), HumanBytes(a.averageSpeed()))
+}
+
+// failOn ends the bar as failed (see Bar.Fail()) when err is an actual error (not nil nor io.EOF).
+func (a *AutoProgress) failOn(err error) {
+	if err != nil && !errors.Is(err, io.EOF) {
+		a.Fail(err)
 	}
 }
 
 // Extra provides the extra information on the right of the progress bar: currrent transfer amount, speed and estimated time left.
 func (a *AutoProgress) Extra(_ *Bar, progressPercent float64) string {
-	elapsed := time.Since(a.start)
-	if a.current == 0 {
-		return fmt.Sprintf(" %d/%d", a.current, a.total)
+	current, total := a.Current(), a.Total()
+	if current == 0 {
+		return fmt.Sprintf(" %d/%d", current, total)
 	}
-	speed := float64(a.current) / elapsed.Seconds()
+	elapsed := a.elapsed()
+	speed := a.speed()
 	switch {
-	case a.total <= 0:
+	case total <= 0:
 		// No total, show current, elapsed and speed.
 		return fmt.Sprintf(" %s, %v elapsed, %s/s  ",
-			HumanBytes(a.current), elapsed.Round(time.Millisecond), HumanBytes(speed))
+			HumanBytes(current), elapsed.Round(time.Millisecond), HumanBytes(speed))
 	case !isDone(progressPercent):
-		bytesLeft := a.total - a.current
-		timeLeft := time.Duration(float64(time.Second) * float64(bytesLeft) / speed)
+		timeLeft, _ := a.eta(progressPercent)
 		return fmt.Sprintf(" %s out of %s, %s elapsed, %s/s, %s remaining  ",
-			HumanBytes(a.current), HumanBytes(a.total),
+			HumanBytes(current), HumanBytes(total),
 			HumanDuration(elapsed), HumanBytes(speed),
 			HumanDuration(timeLeft))
 	default:
 		// Done, show just total, time and speed.
-		clearEOL := "\033[K"
-		if a.NoAnsi {
-			clearEOL = strings.Repeat(" ", 40)
-		}
 		return fmt.Sprintf(" %s in %v, %s/s%s",
-			HumanBytes(a.current), HumanDuration(elapsed), HumanBytes(speed), clearEOL)
+			HumanBytes(current), HumanDuration(elapsed), HumanBytes(a.averageSpeed()), a.clearEOL())
 	}
 }
 
 // AutoProgressReader is a reader proxy associated with a progress bar.
+// The progress accounting is lock free and safe for concurrent use (e.g. parallel ReadAt calls),
+// as long as the underlying reader is.
 type AutoProgressReader struct {
 	AutoProgress
 	r io.Reader
@@ -370,22 +709,109 @@ func (r *AutoProgressReader) Read(p []byte) (n int, err error) {
 	if n > 0 {
 		r.Update(n)
 	}
+	r.failOn(err)
 	return
 }
 
-// End the progress bar: writes a newline and last update if it was skipped
-// earlier due to rate limits. This is called automatically upon Close() by
-// the Auto* wrappers.
+// ErrNoReaderAt is returned by AutoProgressReader.ReadAt when the underlying reader isn't an io.ReaderAt.
+var ErrNoReaderAt = errors.New("progressbar: underlying reader does not implement io.ReaderAt")
+
+// ReadAt reads from the underlying reader if it implements io.ReaderAt (ErrNoReaderAt otherwise)
+// and counts the bytes read toward the progress. Typically used with concurrent chunked reads.
+func (r *AutoProgressReader) ReadAt(p []byte, off int64) (n int, err error) {
+	ra, ok := r.r.(io.ReaderAt)
+	if !ok {
+		return 0, ErrNoReaderAt
+	}
+	n, err = ra.ReadAt(p, off)
+	if n > 0 {
+		r.Update(n)
+	}
+	r.failOn(err)
+	return
+}
+
+// End the progress bar: stops the background renderer if started, draws the latest state (which may
+// have been skipped earlier due to rate limits) and writes a newline (in log mode, prints the final status
+// line if not already printed). This is called automatically upon Close() by
+// the Auto* wrappers. Bars of a multi bar don't write the newline (see MultiBar.End()).
+// The line is then kept, erased or replaced by a summary according to Config.OnFinish (cleared bars of
+// a multi bar are removed from it, see MultiBar.Remove()).
+// Further updates aren't shown and calling End again has no effect. See also Finish().
 func (bar *Bar) End() {
+	bar.Stop()
+	mb, clear := bar.end()
+	switch {
+	case mb == nil:
+	case clear:
+		mb.Remove(bar)
+	case bar.CollapseOnEnd:
+		bar.Collapse()
+	}
+}
+
+// end is End() once the renderer is stopped. Returns the multi bar the bar is part of, if any, and
+// whether the bar is to be removed from it (OnFinishClear).
+func (bar *Bar) end() (*MultiBar, bool) {
 	bar.out.Lock()
-	// Potential unwritten/skipped last update (only if ending before 100%).
-	if len(bar.out.buf) > 0 {
-		_, _ = bar.out.out.Write(bar.out.buf)
-		bar.out.buf = bar.out.buf[:0]
+	defer bar.out.Unlock()
+	if bar.ended {
+		return nil, false
 	}
-	_, _ = bar.out.out.Write([]byte{'\n'})
-	bar.out.needErase = false
-	bar.out.Unlock()
+	bar.ended = true
+	if bar.mb == nil {
+		defer func() { _ = bar.out.flushPartial() }() // after the final line (the multi bar does it at its end).
+	}
+	if bar.logMode() {
+		switch {
+		case bar.JSONEvents:
+			bar.jsonFinish()
+		case bar.mb != nil && bar.mb.LogBlock:
+			// in the multi bar's final lines.
+		case bar.OnFinish == OnFinishSummary:
+			fmt.Fprintf(bar.out.out, "%s\n", bar.summaryLine())
+		case bar.logPending || bar.latestPercent() != bar.lastLogPercent || bar.Current() != bar.lastLogCurrent:
+			// Final status line, if the last state wasn't printed already.
+			bar.logDraw(bar.latestPercent(), true)
+		}
+		return nil, false
+	}
+	if reset := bar.osc.appendReset(nil, &bar.Config); len(reset) > 0 {
+		_, _ = bar.out.out.Write(reset)
+	}
+	switch bar.OnFinish {
+	case OnFinishClear:
+		if bar.mb != nil {
+			return bar.mb, true
+		}
+		_, _ = bar.out.out.Write([]byte(bar.clearLine()))
+	case OnFinishSummary:
+		bar.finalLine = bar.summaryLine() + bar.clearEOL()
+		bar.replaceLine(bar.finalLine)
+	default:
+		// The latest state, which may have been skipped (UpdateInterval) or erased by output to the Writer().
+		bar.finalLine = string(bar.render(nil, bar.latestPercent()))
+		bar.replaceLine(bar.finalLine)
+	}
+	if bar.mb != nil {
+		bar.mb.finished(bar)
+		return bar.mb, false
+	}
+	if bar.OnFinish != OnFinishClear {
+		_, _ = bar.out.out.Write([]byte{'\n'})
+	}
+	atomic.StoreInt32(&bar.out.needErase, 0)
+	return nil, false
+}
+
+// replaceLine writes s on the bar's line (which is the current one for single bars).
+func (bar *Bar) replaceLine(s string) {
+	if bar.hidden {
+		return
+	}
+	buf := append(bar.indexBasedMoveDown(), s...)
+	buf = append(buf, bar.indexBasedMoveUp()...)
+	_, _ = bar.out.out.Write(buf)
 }
 
 func (r *AutoProgressReader) Close() error {
@@ -402,13 +828,15 @@ func NewAutoReader(bar *Bar, r io.Reader, total int64) *AutoProgressReader {
 	res := &AutoProgressReader{}
 	res.Bar = bar
 	res.Bar.Extra = res.Extra
+	res.Bar.Summary = res.Summary
 	res.r = r
-	res.total = total
+	res.SetTotal(total)
 	res.Update(0)
 	return res
 }
 
 // AutoProgressWriter is a writer proxy associated with a progress bar.
+// The progress accounting is lock free and safe for concurrent use, as long as the underlying writer is.
 type AutoProgressWriter struct {
 	AutoProgress
 	w io.Writer
@@ -417,6 +845,7 @@ type AutoProgressWriter struct {
 func (w *AutoProgressWriter) Write(p []byte) (n int, err error) {
 	n, err = w.w.Write(p)
 	w.Update(n)
+	w.failOn(err)
 	return
 }
 
@@ -434,16 +863,18 @@ func NewAutoWriter(bar *Bar, w io.Writer, total int64) *AutoProgressWriter {
 	res := &AutoProgressWriter{}
 	res.Bar = bar
 	res.Bar.Extra = res.Extra
+	res.Bar.Summary = res.Summary
 	res.w = w
-	res.total = total
+	res.SetTotal(total)
 	res.Update(0)
 	return res
 }
 
 // DefaultConfig returns a default configuration for the progress bar with default values:
-// DefaultWidth, color and spinner on, no extra nor prefix, a default update interval of 100ms.
+// DefaultWidth, color and spinner on, no extra nor prefix, a default update interval of 100ms;
+// adjusted for the environment (NO_COLOR, TERM=dumb, FORCE_COLOR, etc..., see ConfigFromEnv()).
 func DefaultConfig() Config {
-	return Config{
+	return ConfigFromEnv(Config{
 		Width:          DefaultWidth,
 		UseColors:      true,
 		Color:          DefaultColor, // GreenBar
@@ -451,7 +882,7 @@ func DefaultConfig() Config {
 		Prefix:         "",
 		UpdateInterval: DefaultMaxUpdateInterval,
 		NoAnsi:         false,
-	}
+	})
 }
 
 // NewBar returns a new progress bar with default settings (DefaultWidth, color and spinner on, no extra nor prefix)
@@ -462,6 +893,7 @@ func NewBar() *Bar {
 		Config: DefaultConfig(),
 		Extra:  nil,
 		out:    screenWriter,
+		id:     newBarID(),
 	}
 }
 
@@ -472,25 +904,32 @@ func (cfg Config) NewBar() *Bar {
 		cfg.Width = DefaultWidth
 	}
 	var out *writer
-	// Default to share screenwriter if nil.
-	if cfg.ScreenWriter == nil {
+	switch {
+	case cfg.ScreenWriter != nil:
+		out = newWriter(cfg.ScreenWriter, cfg.NoAnsi)
+		out.json = cfg.JSONEvents // Writer() lines become message events.
+	case cfg.JSONEvents:
+		out = newWriter(os.Stderr, cfg.NoAnsi) // not the shared one, whose other users don't want JSON lines.
+		out.json = true
+	default:
+		// Default to share screenwriter if nil.
 		out = screenWriter
-	} else {
-		out = &writer{out: cfg.ScreenWriter, buf: make([]byte, 0, ExpectedMaxLength), noAnsi: cfg.NoAnsi}
 	}
 	return &Bar{
 		Config: cfg,
 		Extra:  nil,
 		out:    out,
+		id:     newBarID(),
 	}
 }
 
 // Compile check time of interface implementations.
 var (
-	_ io.Writer = &AutoProgressWriter{}
-	_ io.Closer = &AutoProgressWriter{}
-	_ io.Reader = &AutoProgressReader{}
-	_ io.Closer = &AutoProgressReader{}
+	_ io.Writer   = &AutoProgressWriter{}
+	_ io.Closer   = &AutoProgressWriter{}
+	_ io.Reader   = &AutoProgressReader{}
+	_ io.ReaderAt = &AutoProgressReader{}
+	_ io.Closer   = &AutoProgressReader{}
 )
 
 // --- Multi bar ---
@@ -500,12 +939,71 @@ type MultiBar struct {
 	Config
 	// The progress bars that are part of this multi bar set.
 	Bars []*Bar
+	// Terminal resize notifications (with AutoWidth) and channel closed to stop watching them.
+	resize     chan os.Signal
+	stopResize chan struct{}
+	// Writer (and lock) shared by all the bars, so they can be rearranged while updating.
+	out *writer
+	// Number of bars currently on screen and off screen (see Config.MaxVisibleBars), and last screen row
+	// reserved (relative to the first bar).
+	visible  int
+	hidden   int
+	reserved int
+	// Total bar, if added (see AddTotalBar()) and whether it's shown first, and the counts and earliest start
+	// of the bars removed (see Remove()), which the total keeps accounting for.
+	total          *Bar
+	totalTop       bool
+	removedCurrent int64
+	removedTotal   int64
+	removedStart   int64
+	// Incomplete last line written to Writer(), printed once complete, and set once End()ed.
+	logBuf []byte
+	ended  bool
+	// Time the last block was printed in log mode (see Config.LogBlock).
+	lastBlock time.Time
+	// Aggregate taskbar progress and title last sent (see Config.TaskbarProgress).
+	osc oscProgress
 }
 
 // End should be called at the end to move the cursor to the line after the last multi bar.
+// It also ends all the bars (see Bar.End()), which stops the background renderers if started, and prints
+// the incomplete last line written to Writer() if any.
 func (mb *MultiBar) End() {
-	lastBar := mb.Bars[len(mb.Bars)-1]
-	fmt.Fprintf(lastBar.out.out, "%s\n", lastBar.indexBasedMoveDown())
+	for _, b := range append([]*Bar(nil), mb.Bars...) { // (copy as ending can remove bars)
+		if b != mb.total {
+			b.End()
+		}
+	}
+	if mb.total != nil {
+		mb.total.End() // last, with the final counts.
+	}
+	if mb.resize != nil {
+		signal.Stop(mb.resize)
+		close(mb.stopResize)
+		mb.resize = nil
+	}
+	mb.out.Lock()
+	defer mb.out.Unlock()
+	defer func() { _ = mb.out.flushPartial() }()
+	if len(mb.logBuf) > 0 {
+		_ = mb.printAbove(append(mb.logBuf, '\n'))
+		mb.logBuf = nil
+	}
+	mb.ended = true
+	if reset := mb.osc.appendReset(nil, &mb.Config); len(reset) > 0 {
+		_, _ = mb.out.out.Write(reset)
+	}
+	if len(mb.Bars) == 0 {
+		return // all cleared, the cursor is on the first (now empty) line.
+	}
+	if mb.Bars[0].logMode() {
+		if mb.LogBlock && !mb.Bars[0].JSONEvents {
+			mb.logSummary() // otherwise each bar printed its own final line when ended.
+		}
+		return
+	}
+	to, _ := rowMoves(mb.lastRow())
+	fmt.Fprintf(mb.out.out, "\r%s\n", to)
 }
 
 // PrefixesAlign iterates over the prefixes to left align them such as all the bars are also aligned.
@@ -515,19 +1013,21 @@ func (mb *MultiBar) PrefixesAlign() {
 	// find the alignment of prefixes
 	maxLen := 0
 	for _, b := range mb.Bars {
-		// find the longest prefix before current padding:
+		// find the longest prefix (in terminal columns, including the tree indentation) before current padding:
 		p := strings.TrimSpace(b.Prefix)
-		if len(p) > maxLen {
-			maxLen = len(p)
+		if w := b.depth*len(TreeIndent) + DisplayWidth(p); w > maxLen {
+			maxLen = w
 		}
 	}
 	maxLen++ // extra space before spinner
 	// update the prefixes
 	for _, b := range mb.Bars {
 		b.out.Lock()
-		s := strings.TrimSpace(b.Prefix)
-		b.Prefix = s + strings.Repeat(" ", maxLen-len(s))
+		b.Prefix = PadRight(strings.TrimSpace(b.Prefix), maxLen-b.depth*len(TreeIndent))
 		b.out.Unlock()
+	}
+	// Redraw once all are padded (so for instance the LogBlock lines are aligned).
+	for _, b := range mb.Bars {
 		b.Redraw()
 	}
 }
@@ -565,29 +1065,45 @@ func (cfg Config) NewMultiBar(mbars ...*Bar) *MultiBar {
 // init makes enough space on the terminal for the number of bars and their ExtraLines.
 // It will also clear the screen from the cursor to the end of the screen.
 func (mb *MultiBar) init() {
-	mb.reservespace(true)
+	mb.reservespace()
+	if mb.AutoWidth || mb.MaxVisibleBars == 0 {
+		mb.watchResize()
+	}
 }
 
-func (mb *MultiBar) reservespace(initial bool) {
-	clearAfter := ClearAfter
-	n := len(mb.Bars)
-	if n == 0 {
+// watchResize redraws all the bars, to fit the new width and height, when the terminal is resized.
+func (mb *MultiBar) watchResize() {
+	resize, stop := make(chan os.Signal, 1), make(chan struct{})
+	mb.resize, mb.stopResize = resize, stop
+	notifyResize(resize)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-resize:
+				mb.out.Lock()
+				mb.layout()
+				mb.out.Unlock()
+			}
+		}
+	}()
+}
+
+func (mb *MultiBar) reservespace() {
+	if len(mb.Bars) == 0 {
 		panic("No bars to multi-bar init")
 	}
-	if !initial {
-		clearAfter = "" // no screen clear after initial setup.
-		// Initially we setup including the extra lines above the (first) bar, when doing later updates / adding more bars,
-		// the cursor is on that first bar (so extralines below).
-		if mb.ExtraLines > 0 {
-			clearAfter = fmt.Sprintf("\033[%dA", mb.ExtraLines)
-		}
+	if mb.Bars[0].logMode() {
+		return // no screen space to reserve when printing lines.
 	}
-	mul := (1 + mb.ExtraLines)
-	mb.Bars[0].out.Lock()
-	// Clear from cursor/line to end of screen and make space for all the bars, then back up to the first bar.
-	_, _ = mb.ScreenWriter.Write([]byte("\r" + clearAfter + strings.Repeat("\n", n*mul-1) + // add xxx to newline to see
-		fmt.Sprintf("\033[%dA", (n-1)*mul)))
-	mb.Bars[0].out.Unlock()
+	mb.out.Lock()
+	mb.reserved = mb.lastRow()
+	_, back := rowMoves(mb.reserved)
+	// Clear from cursor/line to end of screen and make space for all the bars (including the extra lines
+	// above the first bar), then back up to the first bar.
+	_, _ = mb.out.out.Write([]byte("\r" + ClearAfter + strings.Repeat("\n", mb.ExtraLines+mb.reserved) + back))
+	mb.out.Unlock()
 }
 
 // Add adds 1 or more progress bars to an existing multibar.
@@ -595,15 +1111,33 @@ func (mb *MultiBar) reservespace(initial bool) {
 // It will reserve space for the new bars and move the cursor up/down as needed
 // for a bar that was already created with NewMultiBar() or NewMultiBarPrefixes().
 func (mb *MultiBar) Add(mbars ...*Bar) *MultiBar {
+	if len(mbars) == 0 {
+		return mb
+	}
+	if mb.out == nil {
+		mb.out = mbars[0].out // the bars all share the first one's writer.
+		for _, b := range mbars {
+			if b.JSONEvents && mb.out == screenWriter {
+				mb.out = newWriter(os.Stderr, mb.NoAnsi) // not the shared one, see Config.NewBar().
+			}
+		}
+	}
+	mb.out.Lock()
 	prev := len(mb.Bars)
 	mb.Bars = append(mb.Bars, mbars...)
-	for i := prev; i < len(mb.Bars); i++ {
-		mb.Bars[i].out.Lock()
-		mb.Bars[i].index = (1 + mb.ExtraLines) * i
-		mb.Bars[i].out.Unlock()
+	for _, b := range mbars {
+		b.out = mb.out
+		b.mb = mb
+		if b.JSONEvents && mb.out != screenWriter {
+			mb.out.json = true
+		}
 	}
-	if prev > 0 {
-		mb.reservespace(false)
+	mb.placeTotal()
+	if prev == 0 {
+		mb.assign() // space reserved by init().
+	} else {
+		mb.layout()
 	}
+	mb.out.Unlock()
 	return mb
 }
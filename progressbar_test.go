@@ -0,0 +1,177 @@
+package progressbar_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fortio.org/progressbar"
+)
+
+// lockedBuffer is a bytes.Buffer safe to read while the partial line timer writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// The incomplete line following a complete one gets the whole PartialLineTimeout, not what's left of the first one.
+func TestPartialLineTimeout(t *testing.T) {
+	var buf lockedBuffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	bar := cfg.NewBar()
+	defer bar.End()
+	w := bar.Writer()
+	_, _ = w.Write([]byte("first"))
+	time.Sleep(progressbar.PartialLineTimeout / 2)
+	_, _ = w.Write([]byte(" line\nsecond"))
+	time.Sleep(progressbar.PartialLineTimeout * 4 / 5)
+	if out := buf.String(); !strings.Contains(out, "first line\n") || strings.Contains(out, "second") {
+		t.Errorf("before the timeout of the second line got %q", out)
+	}
+	time.Sleep(progressbar.PartialLineTimeout)
+	if out := buf.String(); !strings.Contains(out, "second\n") {
+		t.Errorf("after the timeout of the second line got %q", out)
+	}
+}
+
+// Updates in a tight loop still redraw about every UpdateInterval.
+func TestAddRedraws(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	cfg.UpdateInterval = 10 * time.Millisecond
+	bar := cfg.NewCountBar(1 << 62)
+	for start := time.Now(); time.Since(start) < 20*cfg.UpdateInterval; {
+		bar.Add(1)
+	}
+	bar.End()
+	if n := strings.Count(buf.String(), "\r"); n < 10 || n > 30 {
+		t.Errorf("%d redraws in 20 UpdateIntervals, want about 20", n)
+	}
+}
+
+// End() draws the latest state even when its update was skipped (UpdateInterval), for single and multi bars.
+func TestEndDrawsSkippedUpdate(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		var buf bytes.Buffer
+		cfg := progressbar.DefaultConfig()
+		cfg.ScreenWriter = &buf
+		cfg.UpdateInterval = time.Hour
+		var bar *progressbar.Bar
+		var mb *progressbar.MultiBar
+		if multi {
+			mb = cfg.NewMultiBarPrefixes("a", "b")
+			bar = mb.Bars[1]
+		} else {
+			bar = cfg.NewBar()
+		}
+		bar.Progress(10)
+		bar.Progress(20)
+		bar.End()
+		if mb != nil {
+			mb.End()
+		}
+		if out := buf.String(); !strings.Contains(out, "20.0%") {
+			t.Errorf("multi %v: 20.0%% not drawn in %q", multi, out)
+		}
+	}
+}
+
+// Updates that aren't drawn (within UpdateInterval of the previous one) should only cost a few nanoseconds
+// more than the atomic increment of the count (BenchmarkAtomicAdd).
+
+func BenchmarkAtomicAdd(b *testing.B) {
+	var count int64
+	for i := 0; i < b.N; i++ {
+		atomic.AddInt64(&count, 1)
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = io.Discard
+	bar := cfg.NewCountBar(int64(b.N) + 2)
+	bar.Add(1) // first draw, the next ones are within UpdateInterval.
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bar.Add(1)
+	}
+	b.StopTimer()
+	bar.End()
+}
+
+func BenchmarkProgress(b *testing.B) {
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = io.Discard
+	bar := cfg.NewBar()
+	bar.Progress(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bar.Progress(99. * float64(i) / float64(b.N))
+	}
+	b.StopTimer()
+	bar.End()
+}
+
+// Same in log mode (output to a file, e.g. CI logs).
+func BenchmarkAddLogMode(b *testing.B) {
+	f, err := os.Create(filepath.Join(b.TempDir(), "log"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = f
+	bar := cfg.NewCountBar(int64(b.N) + 2)
+	bar.Add(1)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bar.Add(1)
+		}
+	})
+	b.StopTimer()
+	bar.End()
+}
+
+func BenchmarkReadAtParallel(b *testing.B) {
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = io.Discard
+	data := make([]byte, 4096)
+	r := progressbar.NewAutoReader(cfg.NewBar(), bytes.NewReader(data), 1<<62)
+	buf := make([]byte, 1)
+	_, _ = r.ReadAt(buf, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		buf := make([]byte, 1)
+		off := int64(0)
+		for pb.Next() {
+			if _, err := r.ReadAt(buf, off%int64(len(data))); err != nil {
+				b.Error(err)
+				return
+			}
+			off++
+		}
+	})
+	b.StopTimer()
+	r.End()
+}
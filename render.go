@@ -0,0 +1,107 @@
+package progressbar
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultFPS is the number of frames per second drawn by the background renderer when Config.FPS is 0.
+const DefaultFPS = 10
+
+// renderer is the state of a running background render loop.
+type renderer struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start starts a background goroutine redrawing the bar at Config.FPS frames per second.
+// While it runs, Progress() and Add() only record the new state (cheap atomic stores, suitable for
+// hot i/o paths) and the renderer draws the latest state at each frame (the spinner keeps animating
+// even without updates). Stop() (or End()) stops the renderer and draws the final state.
+// Calling Start on an already started bar is a no-op.
+func (bar *Bar) Start() {
+	bar.out.Lock()
+	defer bar.out.Unlock()
+	if bar.renderer != nil {
+		return
+	}
+	r := &renderer{stop: make(chan struct{}), done: make(chan struct{})}
+	bar.renderer = r
+	atomic.StoreInt32(&bar.rendering, 1)
+	fps := bar.FPS
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	go bar.renderLoop(r, time.Second/time.Duration(fps))
+}
+
+// Stop stops the background renderer started with Start() if running, and draws the latest state.
+func (bar *Bar) Stop() {
+	if !bar.stopRenderer() {
+		return
+	}
+	bar.out.Lock()
+	bar.draw(bar.latestPercent())
+	bar.out.Unlock()
+}
+
+// stopRenderer stops the background renderer and waits for it to exit, returns false if it wasn't running.
+func (bar *Bar) stopRenderer() bool {
+	bar.out.Lock()
+	r := bar.renderer
+	bar.renderer = nil
+	atomic.StoreInt32(&bar.rendering, 0)
+	bar.out.Unlock()
+	if r == nil {
+		return false
+	}
+	close(r.stop)
+	<-r.done
+	return true
+}
+
+func (bar *Bar) renderLoop(r *renderer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer func() {
+		ticker.Stop()
+		close(r.done)
+	}()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			bar.renderFrame()
+		}
+	}
+}
+
+// renderFrame draws the latest state if it changed or if there is an animation to update.
+func (bar *Bar) renderFrame() {
+	if atomic.SwapInt32(&bar.dirty, 0) == 0 && !bar.animated() {
+		return
+	}
+	bar.out.Lock()
+	bar.draw(bar.latestPercent())
+	bar.out.Unlock()
+}
+
+// Start starts the background renderer of all the bars, see Bar.Start().
+func (mb *MultiBar) Start() {
+	for _, b := range mb.Bars {
+		b.Start()
+	}
+}
+
+// Stop stops the background renderer of all the bars, see Bar.Stop().
+func (mb *MultiBar) Stop() {
+	for _, b := range mb.Bars {
+		b.Stop()
+	}
+}
+
+// animated returns true when the bar has an animation (spinner or indeterminate bar) that needs redrawing
+// even without new progress.
+func (bar *Bar) animated() bool {
+	return bar.Spinner || (bar.Indeterminate && bar.latestPercent() < 0)
+}
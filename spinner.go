@@ -0,0 +1,64 @@
+package progressbar
+
+import (
+	"time"
+)
+
+// SpinnerStyle is a spinner animation: Frames shown in sequence, each for Interval.
+// Frames should all have the same display width (and include the separator after the spinner if desired).
+type SpinnerStyle struct {
+	Frames   []string
+	Interval time.Duration
+}
+
+// Built-in spinner styles.
+var (
+	// SpinnerDots is the default braille dots spinner (SpinnerChars).
+	SpinnerDots = SpinnerStyle{Frames: SpinnerChars[:], Interval: 100 * time.Millisecond}
+	// SpinnerLine is the classic ASCII -\|/ spinner.
+	SpinnerLine = SpinnerStyle{Frames: []string{"- ", "\\ ", "| ", "/ "}, Interval: 130 * time.Millisecond}
+	// SpinnerArc is a rotating arc.
+	SpinnerArc = SpinnerStyle{Frames: []string{"◜ ", "◠ ", "◝ ", "◞ ", "◡ ", "◟ "}, Interval: 100 * time.Millisecond}
+	// SpinnerBouncingBar is a segment bouncing in brackets.
+	SpinnerBouncingBar = SpinnerStyle{Frames: []string{
+		"[    ] ", "[=   ] ", "[==  ] ", "[=== ] ", "[ ===] ", "[  ==] ",
+		"[   =] ", "[    ] ", "[   =] ", "[  ==] ", "[ ===] ", "[====] ",
+		"[=== ] ", "[==  ] ", "[=   ] ",
+	}, Interval: 80 * time.Millisecond}
+	// SpinnerClock is a clock face going around the hours.
+	SpinnerClock = SpinnerStyle{Frames: []string{
+		"🕛 ", "🕐 ", "🕑 ", "🕒 ", "🕓 ", "🕔 ", "🕕 ", "🕖 ", "🕗 ", "🕘 ", "🕙 ", "🕚 ",
+	}, Interval: 100 * time.Millisecond}
+)
+
+// SpinnerStyles are the built-in spinner styles by name (e.g. to select one from a flag).
+var SpinnerStyles = map[string]*SpinnerStyle{
+	"dots":         &SpinnerDots,
+	"line":         &SpinnerLine,
+	"arc":          &SpinnerArc,
+	"bouncing-bar": &SpinnerBouncingBar,
+	"clock":        &SpinnerClock,
+}
+
+// spinnerStyle returns the bar's spinner style, SpinnerDots by default.
+func (bar *Bar) spinnerStyle() *SpinnerStyle {
+	if bar.SpinnerStyle == nil || len(bar.SpinnerStyle.Frames) == 0 {
+		return &SpinnerDots
+	}
+	return bar.SpinnerStyle
+}
+
+// spinnerFrame returns the current frame of the bar's spinner. The animation is time based and each
+// bar has its own phase (starting from the first frame when first drawn).
+func (bar *Bar) spinnerFrame(now time.Time) string {
+	style := bar.spinnerStyle()
+	if bar.spinnerStart.IsZero() {
+		bar.spinnerStart = now
+	}
+	interval := style.Interval
+	if interval <= 0 {
+		interval = SpinnerDots.Interval
+	}
+	n := int(now.Sub(bar.spinnerStart) / interval)
+	return style.Frames[n%len(style.Frames)]
+}
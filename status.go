@@ -0,0 +1,127 @@
+package progressbar
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatusSpinner is a self animating spinner followed by an updatable status message, for operations
+// whose progress isn't known. Unlike the package level Spinner(), it animates itself once started,
+// writes to the Config's ScreenWriter and its Writer() can be used concurrently to print logs above it.
+type StatusSpinner struct {
+	bar      *Bar
+	finished chan struct{}
+	once     sync.Once
+}
+
+// NewStatusSpinner returns a new status spinner using DefaultConfig(), see Config.NewStatusSpinner().
+func NewStatusSpinner(message string) *StatusSpinner {
+	return DefaultConfig().NewStatusSpinner(message)
+}
+
+// NewStatusSpinner returns a new status spinner with the given initial message. The line is the
+// Config's Prefix (if any), the spinner (see Config.SpinnerStyle) and the message.
+// Call Start() to show and animate it.
+func (cfg Config) NewStatusSpinner(message string) *StatusSpinner {
+	cfg.Spinner = true
+	cfg.Suffix = message
+	cfg.Widgets = []Widget{PrefixWidget{}, SpinnerWidget{}, SuffixWidget{}}
+	if !cfg.AutoWidth {
+		// Erase the leftover of a longer previous message (AutoWidth already does).
+		cfg.Widgets = append(cfg.Widgets, WidgetFunc(func(buf []byte, bar *Bar, _ float64) []byte {
+			return append(buf, bar.clearEOL()...)
+		}))
+	}
+	if cfg.FPS <= 0 {
+		// Redraw at the pace of the animation.
+		if interval := (&Bar{Config: cfg}).spinnerStyle().Interval; interval > 0 {
+			cfg.FPS = int(time.Second / interval)
+		}
+	}
+	return &StatusSpinner{bar: cfg.NewBar(), finished: make(chan struct{})}
+}
+
+// Start shows the spinner and animates it in the background until Stop(), Finish() (or Success(), Fail())
+// is called or until the context is done (same as Stop()).
+func (s *StatusSpinner) Start(ctx context.Context) {
+	s.bar.Start()
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Stop()
+		case <-s.finished:
+		}
+	}()
+}
+
+// UpdateMessage changes the status message shown after the spinner.
+// This is thread safe / acquires the output lock.
+func (s *StatusSpinner) UpdateMessage(message string) {
+	s.bar.out.Lock()
+	s.bar.Suffix = message
+	if s.bar.logMode() {
+		s.bar.logDraw(s.bar.latestPercent(), true) // one line per message in logs.
+	} else {
+		s.bar.draw(s.bar.latestPercent())
+	}
+	s.bar.out.Unlock()
+}
+
+// Writer returns the io.Writer that can be safely used concurrently with the spinner:
+// the spinner line is erased, the output written and the spinner redrawn at the next frame.
+func (s *StatusSpinner) Writer() io.Writer {
+	return s.bar.Writer()
+}
+
+// Stop stops the animation and erases the spinner line.
+func (s *StatusSpinner) Stop() {
+	s.finish(func() {
+		if !s.bar.logMode() {
+			_, _ = s.bar.out.out.Write([]byte(s.bar.clearLine()))
+		}
+	})
+}
+
+// Finish stops the animation and replaces the spinner by symbol (e.g. SuccessSymbol, FailureSymbol)
+// followed by message (or the current status message if empty) and a newline.
+func (s *StatusSpinner) Finish(symbol, message string) {
+	s.finish(func() {
+		if message == "" {
+			message = s.bar.Suffix
+		}
+		line := s.bar.Prefix + symbol + message
+		if !s.bar.logMode() {
+			line = "\r" + line + s.bar.clearEOL()
+		}
+		_, _ = s.bar.out.out.Write([]byte(line + "\n"))
+	})
+}
+
+// Success stops the spinner with the SuccessSymbol and message, see Finish().
+func (s *StatusSpinner) Success(message string) {
+	s.Finish(SuccessSymbol, message)
+}
+
+// Fail stops the spinner with the FailureSymbol and message, see Finish().
+func (s *StatusSpinner) Fail(message string) {
+	s.Finish(FailureSymbol, message)
+}
+
+// finish stops the animation and calls final with the output lock held, only the first time.
+func (s *StatusSpinner) finish(final func()) {
+	s.once.Do(func() {
+		close(s.finished)
+		s.bar.stopRenderer()
+		s.bar.out.Lock()
+		final()
+		if reset := s.bar.osc.appendReset(nil, &s.bar.Config); len(reset) > 0 {
+			_, _ = s.bar.out.out.Write(reset)
+		}
+		atomic.StoreInt32(&s.bar.out.needErase, 0)
+		_ = s.bar.out.flushPartial()
+		s.bar.out.Unlock()
+	})
+}
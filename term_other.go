@@ -0,0 +1,16 @@
+//go:build !linux
+
+package progressbar
+
+import (
+	"io"
+	"os"
+)
+
+// termSize is only implemented on linux for now, elsewhere the configured Width is used.
+func termSize(_ io.Writer) (cols, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// notifyResize is a no-op outside of linux.
+func notifyResize(_ chan<- os.Signal) {}
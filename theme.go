@@ -0,0 +1,97 @@
+package progressbar
+
+import (
+	"fmt"
+)
+
+// Theme is the set of glyphs (and optional colors) used to draw the bar part. Set Config.Theme
+// to use a different one than DefaultTheme for a given bar (or set of bars).
+type Theme struct {
+	// Fill is the glyph of a full cell (Full if empty).
+	Fill string
+	// Empty is the glyph of the cells not yet filled (Space if empty).
+	Empty string
+	// Fractional are the glyphs of a partially filled cell, from 0 (not displayed, can be "") to
+	// len(Fractional)-1 steps. The resolution per cell adapts to the number of steps provided:
+	// 8 for the default blocks, 1 (no partial cell) if nil.
+	Fractional []string
+	// LeftCap and RightCap are always shown around the bar (e.g. "[" and "]").
+	LeftCap, RightCap string
+	// NoColorLeftCap and NoColorRightCap are shown around the bar when not using colors, instead
+	// of the color start and reset sequences (e.g. "◅" and "▻").
+	NoColorLeftCap, NoColorRightCap string
+	// PendingColor and DoneColor, when set, are used instead of Config.Color while in progress and once done.
+	PendingColor, DoneColor string
+}
+
+// Built-in themes.
+var (
+	// DefaultTheme is the block characters with 8 steps per cell resolution.
+	DefaultTheme = Theme{
+		Fill: Full, Empty: Space, Fractional: FractionalBlocks[:],
+		NoColorLeftCap: "◅", NoColorRightCap: "▻", // or "◢" "◣"
+	}
+	// ASCIITheme is for consoles without block glyphs: [=====>    ].
+	ASCIITheme = Theme{
+		Fill: "=", Empty: " ", Fractional: []string{"", ">"},
+		LeftCap: "[", RightCap: "]",
+	}
+	// BrailleTheme uses braille dots with 8 steps per cell resolution.
+	BrailleTheme = Theme{
+		Fill: "⣿", Empty: " ", Fractional: []string{"", "⡀", "⡄", "⡆", "⡇", "⣇", "⣧", "⣷"},
+		NoColorLeftCap: "⢸", NoColorRightCap: "⡇",
+	}
+	// ShadedTheme uses shades blocks: ████▓░░░.
+	ShadedTheme = Theme{
+		Fill: "█", Empty: "░", Fractional: []string{"", "▒", "▓"},
+	}
+	// DotsTheme uses dots: ●●●●◐○○○.
+	DotsTheme = Theme{
+		Fill: "●", Empty: "○", Fractional: []string{"", "◐"},
+	}
+)
+
+// Validate checks that all the glyphs of the theme occupy exactly 1 terminal column
+// (otherwise the bar width would be off). Empty Fill and Empty mean the defaults and the first
+// Fractional entry is never displayed so they aren't checked. Bars with an invalid theme use
+// DefaultTheme instead.
+func (t *Theme) Validate() error {
+	glyphs := []string{t.Fill, t.Empty}
+	if len(t.Fractional) > 1 {
+		glyphs = append(glyphs, t.Fractional[1:]...)
+	}
+	for _, g := range glyphs {
+		if g == "" {
+			continue
+		}
+		if w := DisplayWidth(g); w != 1 {
+			return fmt.Errorf("theme glyph %q is %d columns wide instead of 1", g, w)
+		}
+	}
+	return nil
+}
+
+// theme returns the bar's theme with the defaults applied, DefaultTheme if it's invalid (see Validate()).
+// Called with the lock held.
+func (bar *Bar) theme() Theme {
+	if bar.Theme == nil {
+		return DefaultTheme
+	}
+	if bar.Theme != bar.checkedTheme {
+		bar.checkedTheme, bar.themeValid = bar.Theme, bar.Theme.Validate() == nil
+	}
+	if !bar.themeValid {
+		return DefaultTheme
+	}
+	t := *bar.Theme
+	if t.Fill == "" {
+		t.Fill = Full
+	}
+	if t.Empty == "" {
+		t.Empty = Space
+	}
+	if len(t.Fractional) == 0 {
+		t.Fractional = []string{""}
+	}
+	return t
+}
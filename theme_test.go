@@ -0,0 +1,42 @@
+package progressbar_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"fortio.org/progressbar"
+)
+
+func TestThemeValidate(t *testing.T) {
+	for _, theme := range []progressbar.Theme{progressbar.DefaultTheme, progressbar.ASCIITheme,
+		progressbar.BrailleTheme, progressbar.ShadedTheme, progressbar.DotsTheme, {}} {
+		if err := theme.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error %v", theme, err)
+		}
+	}
+	for _, theme := range []progressbar.Theme{
+		{Fill: "🟩"},
+		{Empty: "ab"},
+		{Fractional: []string{"", "▌", "世"}},
+	} {
+		if err := theme.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", theme)
+		}
+	}
+}
+
+func TestInvalidThemeUsesDefault(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := progressbar.DefaultConfig()
+	cfg.ScreenWriter = &buf
+	cfg.NoAnsi = true
+	cfg.UpdateInterval = 0
+	cfg.Theme = &progressbar.Theme{Fill: "🟩", Empty: "⬜"}
+	bar := cfg.NewBar()
+	bar.Progress(50)
+	out := buf.String()
+	if strings.Contains(out, "🟩") || !strings.Contains(out, progressbar.Full) {
+		t.Errorf("expected the default theme, got %q", out)
+	}
+}
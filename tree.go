@@ -0,0 +1,105 @@
+package progressbar
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// TreeIndent is the indentation, per level, of the prefix of tree bars (see NewChild()).
+const TreeIndent = "  "
+
+// ErrNoMultiBar is returned by NewChild when the parent bar isn't part of a multi bar.
+var ErrNoMultiBar = errors.New("progressbar: parent bar isn't part of a multi bar")
+
+// NewChild creates a new bar with the same config as bar and the given prefix, shown indented under it
+// (after its previous children). The parent bar must be part of a multi bar, which the child is added to
+// (ErrNoMultiBar otherwise). See also Config.DeriveFromChildren and Config.CollapseOnEnd.
+func (bar *Bar) NewChild(prefix string) (*Bar, error) {
+	bar.out.Lock()
+	mb := bar.mb
+	bar.out.Unlock()
+	if mb == nil {
+		return nil, ErrNoMultiBar
+	}
+	cfg := bar.Config
+	cfg.Prefix = prefix
+	child := cfg.NewBar()
+	mb.out.Lock()
+	child.out = mb.out
+	child.mb = mb
+	child.parent = bar
+	child.depth = bar.depth + 1
+	// After the parent's subtree.
+	i := mb.position(bar) + 1 + len(bar.descendants())
+	mb.Bars = append(mb.Bars[:i], append([]*Bar{child}, mb.Bars[i:]...)...)
+	bar.children = append(bar.children, child)
+	bar.branch = true
+	mb.layout()
+	mb.out.Unlock()
+	mb.PrefixesAlign()
+	return child, nil
+}
+
+// Children returns the bars created with NewChild() (and not removed).
+func (bar *Bar) Children() []*Bar {
+	bar.out.Lock()
+	defer bar.out.Unlock()
+	return append([]*Bar(nil), bar.children...)
+}
+
+// Collapse removes the children of the bar (and their own children) from the screen, e.g. once complete.
+// See also Config.CollapseOnEnd.
+func (bar *Bar) Collapse() {
+	bar.out.Lock()
+	mb := bar.mb
+	children := append([]*Bar(nil), bar.children...)
+	bar.out.Unlock()
+	if mb != nil && len(children) > 0 {
+		mb.Remove(children...)
+	}
+}
+
+// descendants returns the children of the bar, their children, etc... in display order.
+// Called with the lock held.
+func (bar *Bar) descendants() []*Bar {
+	var res []*Bar
+	for _, c := range bar.children {
+		res = append(res, c)
+		res = append(res, c.descendants()...)
+	}
+	return res
+}
+
+// deriveFromChildren updates the progress of the bar from its children (see Config.DeriveFromChildren).
+// Called with the lock held.
+func (bar *Bar) deriveFromChildren() {
+	n := len(bar.children)
+	if n == 0 {
+		return
+	}
+	current, total, start := sumCounts(bar.children)
+	if total > 0 {
+		atomic.StoreInt64(&bar.current, current)
+		atomic.StoreInt64(&bar.total, total)
+		setFlag(&bar.counting)
+	} else {
+		sum := 0.
+		for _, c := range bar.children {
+			p := c.latestPercent()
+			switch {
+			case p > 100:
+				p = 100
+			case p < 0:
+				p = 0
+			}
+			sum += p
+		}
+		atomic.StoreInt32(&bar.counting, 0)
+		atomic.StoreUint64(&bar.percentBits, math.Float64bits(sum/float64(n)))
+	}
+	if start != 0 {
+		atomic.StoreInt64(&bar.startNano, start)
+	}
+	bar.progress(bar.latestPercent())
+}
@@ -0,0 +1,339 @@
+package progressbar
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Widget renders one element of a progress bar line (prefix, spinner, bar, percentage, etc...).
+// The line is the concatenation of the Config.Widgets (or DefaultWidgets() when nil) in order.
+type Widget interface {
+	// Render appends the widget's output to buf and returns the extended buffer.
+	// It is called with the bar's output lock held so it must not call the locking
+	// methods of the Bar (UpdatePrefix, Progress, etc...).
+	Render(buf []byte, bar *Bar, progressPercent float64) []byte
+}
+
+// WidgetFunc adapts a function to the Widget interface.
+type WidgetFunc func(buf []byte, bar *Bar, progressPercent float64) []byte
+
+// Render calls f.
+func (f WidgetFunc) Render(buf []byte, bar *Bar, progressPercent float64) []byte {
+	return f(buf, bar, progressPercent)
+}
+
+// DefaultWidgets returns the widgets matching the classic layout driven by the Config fields:
+// Prefix, spinner, bar, percentage, Suffix and Extra.
+func DefaultWidgets() []Widget {
+	return []Widget{PrefixWidget{}, SpinnerWidget{}, BarWidget{}, PercentWidget{}, SuffixWidget{}, ExtraWidget{}}
+}
+
+// PrefixWidget shows the bar's Prefix, indented according to the depth for tree bars (see NewChild()).
+type PrefixWidget struct{}
+
+func (PrefixWidget) Render(buf []byte, bar *Bar, _ float64) []byte {
+	for i := 0; i < bar.depth; i++ {
+		buf = append(buf, TreeIndent...)
+	}
+	return append(buf, bar.Prefix...)
+}
+
+// SuffixWidget shows the bar's Suffix, or once finished the final message (see Bar.Finish()), preceded
+// by the status symbol when there is no spinner to show it.
+type SuffixWidget struct{}
+
+func (SuffixWidget) Render(buf []byte, bar *Bar, _ float64) []byte {
+	if bar.status != NotFinished && !bar.Spinner && bar.status != FinishSuccess {
+		buf = append(buf, ' ')
+		buf = append(buf, bar.status.Symbol()...)
+	}
+	if bar.finalMessage != "" {
+		if bar.Spinner || bar.status == FinishSuccess {
+			buf = append(buf, ' ')
+		}
+		return append(buf, bar.finalMessage...)
+	}
+	return append(buf, bar.Suffix...)
+}
+
+// ExtraWidget shows the result of the bar's Extra function, if set (and not replaced by a final message).
+type ExtraWidget struct{}
+
+func (ExtraWidget) Render(buf []byte, bar *Bar, progressPercent float64) []byte {
+	if bar.Extra == nil || bar.finalMessage != "" {
+		return buf
+	}
+	return append(buf, bar.Extra(bar, progressPercent)...)
+}
+
+// TextWidget shows a fixed text.
+type TextWidget string
+
+func (t TextWidget) Render(buf []byte, _ *Bar, _ float64) []byte {
+	return append(buf, t...)
+}
+
+// SpinnerWidget shows the spinner (if Config.Spinner is true), animated according to Config.SpinnerStyle,
+// and DoneSpinner once done (or the status symbol once finished, see Bar.Finish()).
+type SpinnerWidget struct{}
+
+func (SpinnerWidget) Render(buf []byte, bar *Bar, progressPercent float64) []byte {
+	if !bar.Spinner {
+		return buf
+	}
+	if bar.status != NotFinished {
+		return append(buf, bar.status.Symbol()...)
+	}
+	if isDone(progressPercent) {
+		return append(buf, DoneSpinner...)
+	}
+	if bar.logMode() {
+		// no animation in log lines.
+		return append(buf, strings.Repeat(" ", DisplayWidth(bar.spinnerStyle().Frames[0]))...)
+	}
+	return append(buf, bar.spinnerFrame(time.Now())...)
+}
+
+// BarWidget shows the bar itself, when the percentage is known (between 0 and 100), drawn with the
+// Config.Theme glyphs.
+type BarWidget struct{}
+
+func (BarWidget) Render(buf []byte, bar *Bar, progressPercent float64) []byte {
+	if progressPercent < 0 && bar.Indeterminate && !bar.logMode() {
+		return bar.appendIndeterminateBar(buf, time.Now())
+	}
+	if progressPercent < 0 || progressPercent > 100 {
+		return buf
+	}
+	theme := bar.theme()
+	steps := len(theme.Fractional)
+	width := float64(bar.barWidth)
+	count := int(float64(steps)*width*progressPercent/100. + 0.5)
+	fullCount := count / steps
+	remainder := count % steps
+	spaceCount := int(width) - fullCount - 1
+	if remainder == 0 {
+		spaceCount++
+	}
+	color := bar.Color
+	statusColor := bar.status.color()
+	switch {
+	case statusColor != "":
+		color = statusColor
+	case theme.DoneColor != "" && isDone(progressPercent):
+		color = theme.DoneColor
+	case theme.PendingColor != "" && !isDone(progressPercent):
+		color = theme.PendingColor
+	}
+	reset := Reset
+	useColors := bar.UseColors && !bar.noAnsi()
+	if !useColors {
+		color = theme.NoColorLeftCap
+		reset = theme.NoColorRightCap
+	}
+	buf = append(buf, theme.LeftCap...)
+	if useColors && len(bar.Gradient) > 0 && statusColor == "" {
+		bg := DefaultBackground
+		if bar.Background != nil {
+			bg = *bar.Background
+		}
+		if bar.GradientBlend {
+			buf = bar.appendBlendedBar(buf, &theme, bg, width, fullCount, remainder, spaceCount)
+			return append(buf, theme.RightCap...)
+		}
+		color = BarColor(gradientColor(bar.Gradient, progressPercent, false), bg, bar.ColorMode)
+	}
+	buf = append(buf, color...)
+	buf = append(buf, strings.Repeat(theme.Fill, fullCount)...)
+	buf = append(buf, theme.Fractional[remainder]...)
+	buf = append(buf, strings.Repeat(theme.Empty, spaceCount)...)
+	buf = append(buf, reset...)
+	return append(buf, theme.RightCap...)
+}
+
+// IndeterminateSweep is the time it takes the Config.Indeterminate segment to cross the bar (one way).
+const IndeterminateSweep = 1500 * time.Millisecond
+
+// appendIndeterminateBar appends a bar with a segment (a quarter of the width) bouncing from one end to the other.
+func (bar *Bar) appendIndeterminateBar(buf []byte, now time.Time) []byte {
+	theme := bar.theme()
+	if bar.indeterminateStart.IsZero() {
+		bar.indeterminateStart = now
+	}
+	width := bar.barWidth
+	segment := (width + 3) / 4
+	pos := 0
+	if travel := width - segment; travel > 0 {
+		phase := float64(now.Sub(bar.indeterminateStart)%(2*IndeterminateSweep)) / float64(IndeterminateSweep)
+		if phase > 1 {
+			phase = 2 - phase // coming back.
+		}
+		pos = int(phase*float64(travel) + 0.5)
+	}
+	color, reset := bar.Color, Reset
+	if theme.PendingColor != "" {
+		color = theme.PendingColor
+	}
+	if !bar.UseColors || bar.noAnsi() {
+		color = theme.NoColorLeftCap
+		reset = theme.NoColorRightCap
+	}
+	buf = append(buf, theme.LeftCap...)
+	buf = append(buf, color...)
+	buf = append(buf, strings.Repeat(theme.Empty, pos)...)
+	buf = append(buf, strings.Repeat(theme.Fill, segment)...)
+	buf = append(buf, strings.Repeat(theme.Empty, width-pos-segment)...)
+	buf = append(buf, reset...)
+	return append(buf, theme.RightCap...)
+}
+
+// appendBlendedBar appends a bar where each filled cell gets the gradient color of its position.
+func (bar *Bar) appendBlendedBar(buf []byte, theme *Theme, bg RGB, width float64, fullCount, remainder, spaceCount int) []byte {
+	buf = append(buf, bg.Bg(bar.ColorMode)...)
+	last := ""
+	setColor := func(cell int) {
+		c := gradientColor(bar.Gradient, 100.*(float64(cell)+0.5)/width, true).Fg(bar.ColorMode)
+		if c != last { // only emit changes (fewer bytes, especially in 16 or 256 colors mode).
+			buf = append(buf, c...)
+			last = c
+		}
+	}
+	for i := 0; i < fullCount; i++ {
+		setColor(i)
+		buf = append(buf, theme.Fill...)
+	}
+	if remainder > 0 {
+		setColor(fullCount)
+		buf = append(buf, theme.Fractional[remainder]...)
+	}
+	buf = append(buf, strings.Repeat(theme.Empty, spaceCount)...)
+	return append(buf, Reset...)
+}
+
+// PercentWidget shows the percentage (unless Config.NoPercent is set), when known.
+type PercentWidget struct {
+	// Format to use instead of the default " %.1f%%".
+	Format string
+}
+
+func (p PercentWidget) Render(buf []byte, bar *Bar, progressPercent float64) []byte {
+	if bar.NoPercent || progressPercent < 0 || progressPercent > 100 {
+		return buf
+	}
+	format := p.Format
+	if format == "" {
+		format = " %.1f%%"
+	}
+	return append(buf, fmt.Sprintf(format, progressPercent)...)
+}
+
+// CounterWidget shows the current and total counts (" current/total" or just " current" when the total
+// isn't known).
+type CounterWidget struct {
+	// Format a count, defaults to the plain integer value. Use for instance HumanBytes[int64].
+	Format func(int64) string
+}
+
+func (c CounterWidget) Render(buf []byte, bar *Bar, _ float64) []byte {
+	format := c.Format
+	if format == nil {
+		format = func(n int64) string { return fmt.Sprint(n) }
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, format(bar.Current())...)
+	if total := bar.Total(); total > 0 {
+		buf = append(buf, '/')
+		buf = append(buf, format(total)...)
+	}
+	return buf
+}
+
+// SpeedWidget shows the rate of the counts (see Bar.Estimator).
+type SpeedWidget struct {
+	// Format a rate, defaults to HumanBytes followed by "/s".
+	Format func(float64) string
+}
+
+func (s SpeedWidget) Render(buf []byte, bar *Bar, _ float64) []byte {
+	speed := bar.speed()
+	if speed <= 0 {
+		return buf
+	}
+	buf = append(buf, ' ')
+	if s.Format == nil {
+		buf = append(buf, HumanBytes(speed)...)
+		return append(buf, "/s"...)
+	}
+	return append(buf, s.Format(speed)...)
+}
+
+// ElapsedWidget shows the time elapsed since the start of the progress.
+type ElapsedWidget struct{}
+
+func (ElapsedWidget) Render(buf []byte, bar *Bar, _ float64) []byte {
+	elapsed := bar.elapsed()
+	if elapsed <= 0 {
+		return buf
+	}
+	buf = append(buf, ' ')
+	return append(buf, HumanDuration(elapsed)...)
+}
+
+// ETAWidget shows the estimated time remaining, based on the counts when available
+// or extrapolated from the percentage otherwise.
+type ETAWidget struct{}
+
+func (ETAWidget) Render(buf []byte, bar *Bar, progressPercent float64) []byte {
+	eta, ok := bar.eta(progressPercent)
+	if !ok {
+		return buf
+	}
+	buf = append(buf, " ETA "...)
+	return append(buf, HumanDuration(eta)...)
+}
+
+// elapsed returns the time since the start of the progress (0 if not started).
+func (bar *Bar) elapsed() time.Duration {
+	start := atomic.LoadInt64(&bar.startNano)
+	if start == 0 {
+		return 0
+	}
+	return time.Duration(time.Now().UnixNano() - start)
+}
+
+// speed returns the rate of the counts according to the bar's Estimator, or the average
+// since the start if there is none (0 if unknown).
+func (bar *Bar) speed() float64 {
+	if bar.Estimator != nil {
+		return bar.Estimator.Rate()
+	}
+	return bar.averageSpeed()
+}
+
+// averageSpeed returns the average rate of the counts since the start (0 if unknown).
+func (bar *Bar) averageSpeed() float64 {
+	current := bar.Current()
+	elapsed := bar.elapsed().Seconds()
+	if current <= 0 || elapsed <= 0 {
+		return 0
+	}
+	return float64(current) / elapsed
+}
+
+// eta returns the estimated time remaining, false if it can't be estimated.
+func (bar *Bar) eta(progressPercent float64) (time.Duration, bool) {
+	if total := bar.Total(); total > 0 {
+		speed := bar.speed()
+		if speed <= 0 {
+			return 0, false
+		}
+		return time.Duration(float64(time.Second) * float64(total-bar.Current()) / speed), true
+	}
+	elapsed := bar.elapsed()
+	if progressPercent <= 0 || progressPercent > 100 || elapsed <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(elapsed) * (100. - progressPercent) / progressPercent), true
+}
@@ -0,0 +1,172 @@
+package progressbar
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis is appended to text truncated by Truncate (e.g. lines elided to fit the terminal width).
+const Ellipsis = "…"
+
+// ansiSequenceLen returns the length of the ANSI escape sequence at the start of s (0 if there is none).
+// Handles CSI (ESC [ ... final byte) and OSC (ESC ] ... BEL or ESC \) sequences.
+func ansiSequenceLen(s string) int {
+	if len(s) < 2 || s[0] != '\033' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\033' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	default:
+		return 2
+	}
+}
+
+// StripAnsi returns s without its ANSI escape sequences (colors, cursor movements, etc...).
+func StripAnsi(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String()
+}
+
+// RuneWidth returns the number of terminal columns the rune occupies: 0 for control characters
+// and combining marks, 2 for East Asian wide/fullwidth characters and emojis, 1 otherwise.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300:
+		return 1 // fast path for ASCII and latin-1.
+	case r >= 0x1160 && r <= 0x11ff: // Hangul Jamo medial vowels and final consonants.
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case inWideTable(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// DisplayWidth returns the number of terminal columns s occupies, taking into account wide characters
+// (CJK, emojis), combining marks and skipping ANSI escape sequences. Use this instead of len() to pad or
+// align text (e.g. in Extra functions).
+func DisplayWidth(s string) int {
+	w := 0
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		w += RuneWidth(r)
+	}
+	return w
+}
+
+// Truncate returns the beginning of s fitting in width columns, with the Ellipsis at the end
+// if it had to be shortened (empty if even the Ellipsis doesn't fit). ANSI sequences before the cut are
+// preserved (but not counted).
+func Truncate(s string, width int) string {
+	if DisplayWidth(s) <= width {
+		return s
+	}
+	width -= DisplayWidth(Ellipsis)
+	if width < 0 {
+		return ""
+	}
+	w := 0
+	i := 0
+	for i < len(s) {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := RuneWidth(r)
+		if w+rw > width {
+			break
+		}
+		w += rw
+		i += size
+	}
+	return s[:i] + Ellipsis
+}
+
+// PadRight returns s followed by enough spaces to occupy width columns (s unchanged if already wider).
+func PadRight(s string, width int) string {
+	n := width - DisplayWidth(s)
+	if n <= 0 {
+		return s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+// wideTable is the sorted list of ranges of East Asian Wide (W) and Fullwidth (F) code points,
+// including the emojis with default emoji presentation.
+var wideTable = [...]struct{ lo, hi rune }{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec}, {0x23f0, 0x23f0}, {0x23f3, 0x23f3},
+	{0x25fd, 0x25fe}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce}, {0x26d4, 0x26d4}, {0x26ea, 0x26ea},
+	{0x26f2, 0x26f3}, {0x26f5, 0x26f5}, {0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27b0, 0x27b0}, {0x27bf, 0x27bf}, {0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf}, {0xa960, 0xa97f}, {0xac00, 0xd7a3},
+	{0xf900, 0xfaff}, {0xfe10, 0xfe19}, {0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6},
+	{0x16fe0, 0x16fe4}, {0x17000, 0x18aff}, {0x1b000, 0x1b2ff}, {0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f202}, {0x1f210, 0x1f23b}, {0x1f240, 0x1f248},
+	{0x1f250, 0x1f251}, {0x1f260, 0x1f265}, {0x1f300, 0x1f320}, {0x1f32d, 0x1f335}, {0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca}, {0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e}, {0x1f440, 0x1f440}, {0x1f442, 0x1f4fc}, {0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4}, {0x1f5fb, 0x1f64f},
+	{0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2}, {0x1f6d5, 0x1f6d7}, {0x1f6dc, 0x1f6df},
+	{0x1f6eb, 0x1f6ec}, {0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb}, {0x1f7f0, 0x1f7f0}, {0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945}, {0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+func inWideTable(r rune) bool {
+	if r < wideTable[0].lo || r > wideTable[len(wideTable)-1].hi {
+		return false
+	}
+	lo, hi := 0, len(wideTable)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < wideTable[mid].lo:
+			hi = mid - 1
+		case r > wideTable[mid].hi:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
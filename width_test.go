@@ -0,0 +1,130 @@
+package progressbar_test
+
+import (
+	"testing"
+
+	"fortio.org/progressbar"
+)
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want int
+	}{
+		{'a', 1},
+		{'é', 1},
+		{'\t', 0},
+		{0x7f, 0},
+		{0x301, 0},  // combining acute accent.
+		{0x200d, 0}, // zero width joiner.
+		{'世', 2},
+		{'ｱ', 1}, // halfwidth katakana.
+		{'Ａ', 2}, // fullwidth latin.
+		{'한', 2},
+		{0x1160, 0}, // hangul jamo medial vowel.
+		{'🚀', 2},
+		{'✓', 1},
+		{'⌚', 2},
+		{'█', 1},
+	}
+	for _, tt := range tests {
+		if got := progressbar.RuneWidth(tt.r); got != tt.want {
+			t.Errorf("RuneWidth(%q) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"caf\u00e9", 4},
+		{"cafe\u0301", 4}, // e + combining accent.
+		{"日本語", 6},
+		{"a🚀b", 4},
+		{"\033[32mgreen\033[0m", 5},       // CSI color.
+		{"\033[38;2;1;2;3mrgb\033[K", 3},  // CSI truecolor and erase.
+		{"\033]0;title\atext", 4},         // OSC ended by BEL.
+		{"\033]8;;http://x\033\\link", 4}, // OSC ended by ST.
+		{"\033[12", 0},                    // unterminated CSI.
+		{"\033]9;4;1;50", 0},              // unterminated OSC.
+		{"x\033(By", 3},                   // other escape: ESC and the next byte skipped.
+		{"\033[1m日本\033[0m語 ok", 9},       // mixed.
+	}
+	for _, tt := range tests {
+		if got := progressbar.DisplayWidth(tt.s); got != tt.want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStripAnsi(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"plain", "plain"},
+		{"\033[32mgreen\033[0m", "green"},
+		{"a\033[2Kb\033[1Ac", "abc"},
+		{"\033]2;title\a日本", "日本"},
+		{"\033]8;;url\033\\link\033]8;;\033\\", "link"},
+	}
+	for _, tt := range tests {
+		if got := progressbar.StripAnsi(tt.s); got != tt.want {
+			t.Errorf("StripAnsi(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hello", 5, "hello"},
+		{"hello", 10, "hello"},
+		{"hello world", 6, "hello…"},
+		{"hello world", 1, "…"},
+		{"hello world", 0, ""},
+		{"hello world", -3, ""},
+		{"", 0, ""},
+		{"日本語", 6, "日本語"},
+		{"日本語", 5, "日本…"},
+		{"日本語", 4, "日…"}, // a wide rune doesn't fit in the 1 column left.
+		{"日本語", 2, "…"},
+		{"cafe\u0301 au lait", 5, "cafe\u0301…"}, // combining mark kept with its base.
+		{"a🚀b🚀c", 4, "a🚀…"},
+		{"\033[32mgreen text\033[0m", 6, "\033[32mgreen…"},
+		{"\033[1m\033[0mabc", 2, "\033[1m\033[0ma…"},
+	}
+	for _, tt := range tests {
+		got := progressbar.Truncate(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if w := progressbar.DisplayWidth(got); tt.width >= 0 && w > tt.width {
+			t.Errorf("Truncate(%q, %d) = %q is %d columns wide", tt.s, tt.width, got, w)
+		}
+	}
+}
+
+func TestPadRight(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"ab", 4, "ab  "},
+		{"日本", 5, "日本 "},
+		{"\033[1mab\033[0m", 3, "\033[1mab\033[0m "},
+		{"toolong", 3, "toolong"},
+	}
+	for _, tt := range tests {
+		if got := progressbar.PadRight(tt.s, tt.width); got != tt.want {
+			t.Errorf("PadRight(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}